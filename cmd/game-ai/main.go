@@ -1,7 +0,0 @@
-package main
-
-import "github.com/iryoda/war/pkg"
-
-func main() {
-	pkg.AiXAi()
-}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/iryoda/war/pkg"
+)
+
+type command struct {
+	name  string
+	usage string
+	// run parses args with fs and runs the command
+	run func(fs *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{
+		name:  "play",
+		usage: "two players on the same keyboard",
+		run: func(fs *flag.FlagSet, args []string) error {
+			cfg := pkg.MatchConfig{}
+			cfg.RegisterFlags(fs, 1)
+			fs.Parse(args)
+
+			return pkg.Run(cfg)
+		},
+	},
+	{
+		name:  "watch",
+		usage: "two models playing each other",
+		run: func(fs *flag.FlagSet, args []string) error {
+			cfg := pkg.MatchConfig{}
+			cfg.RegisterFlags(fs, 2)
+			cfg.RegisterModelFlags(fs)
+			fs.BoolVar(&cfg.Headless, "headless", false, "play without a window")
+			fs.Parse(args)
+
+			return pkg.AiXAi(cfg)
+		},
+	},
+	{
+		name:  "vs-ai",
+		usage: "play blue against a model",
+		run: func(fs *flag.FlagSet, args []string) error {
+			cfg := pkg.MatchConfig{}
+			cfg.RegisterFlags(fs, 1)
+			fs.StringVar(&cfg.RedModel, "red", pkg.BEST_MODEL_FILE, "model file playing red")
+			fs.Parse(args)
+
+			return pkg.PalyerXAi(cfg)
+		},
+	},
+	{
+		name:  "train",
+		usage: "train models headless",
+		run: func(fs *flag.FlagSet, args []string) error {
+			cfg := pkg.TrainConfig{}
+			cfg.RegisterFlags(fs)
+			fs.Parse(args)
+
+			return pkg.RunTrain(cfg)
+		},
+	},
+	{
+		name:  "eval",
+		usage: "play two models headless and count the wins",
+		run: func(fs *flag.FlagSet, args []string) error {
+			cfg := pkg.EvalConfig{}
+			cfg.RegisterFlags(fs)
+			fs.Parse(args)
+
+			res, err := pkg.Eval(cfg)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("BLUE:", res.BlueWins, "RED:", res.RedWins, "DRAWS:", res.Draws)
+			return nil
+		},
+	},
+	{
+		name:  "tournament",
+		usage: "rank model files with a round-robin",
+		run: func(fs *flag.FlagSet, args []string) error {
+			cfg := pkg.TournamentConfig{}
+			cfg.RegisterFlags(fs)
+			fs.Usage = func() {
+				fmt.Fprintln(fs.Output(), "usage: war tournament [flags] <model>...")
+				fs.PrintDefaults()
+			}
+			fs.Parse(args)
+			cfg.Models = fs.Args()
+
+			standings, _, err := pkg.RunTournament(cfg)
+			if err != nil {
+				return err
+			}
+
+			return pkg.WriteStandings(standings, os.Stdout)
+		},
+	},
+	{
+		name:  "ladder",
+		usage: "show the model ratings, or place model files on the ladder",
+		run: func(fs *flag.FlagSet, args []string) error {
+			file := fs.String("ladder", pkg.LADDER_FILE, "ladder file")
+			seed := fs.Uint64("seed", 0, "seed of the placement games, a random one is picked when 0")
+			workers := fs.Int("workers", 0, "matches played at the same time, 0 uses every CPU")
+			rules := pkg.WarRules{}
+			pkg.RegisterWarRulesFlag(fs, &rules)
+			fs.Usage = func() {
+				fmt.Fprintln(fs.Output(), "usage: war ladder [flags] [model]...")
+				fs.PrintDefaults()
+			}
+			fs.Parse(args)
+
+			if *seed == 0 {
+				*seed = pkg.NewSeed()
+			}
+
+			err := pkg.UpdateLadder(*file, func(l *pkg.Ladder) error {
+				for i, m := range fs.Args() {
+					fmt.Println("PLACING:", m, "SEED:", *seed+uint64(i))
+					if err := l.Place(m, *seed+uint64(i), *workers, rules); err != nil {
+						return err
+					}
+				}
+
+				return l.Write(os.Stdout)
+			})
+			return err
+		},
+	},
+	{
+		name:  "replay",
+		usage: "play a recorded game again",
+		run: func(fs *flag.FlagSet, args []string) error {
+			tick := fs.Int("tick", 0, "tick to start watching at")
+			headless := fs.Bool("headless", false, "verify the replay without a window")
+			fs.Usage = func() {
+				fmt.Fprintln(fs.Output(), "usage: war replay [flags] <file>")
+				fs.PrintDefaults()
+			}
+			fs.Parse(args)
+
+			if fs.NArg() != 1 {
+				fs.Usage()
+				return flag.ErrHelp
+			}
+
+			r, err := pkg.LoadReplay(fs.Arg(0))
+			if err != nil {
+				return err
+			}
+
+			if *headless {
+				if err := pkg.VerifyReplay(r); err != nil {
+					return err
+				}
+
+				fmt.Println("REPLAY OK, WINNER:", r.Winner)
+				return nil
+			}
+
+			pkg.WatchReplay(r, *tick)
+			return nil
+		},
+	},
+	{
+		name:  "inspect-model",
+		usage: "print the layers of model files",
+		run: func(fs *flag.FlagSet, args []string) error {
+			fs.Usage = func() {
+				fmt.Fprintln(fs.Output(), "usage: war inspect-model <file>...")
+			}
+			fs.Parse(args)
+
+			if fs.NArg() == 0 {
+				fs.Usage()
+				return flag.ErrHelp
+			}
+
+			for _, f := range fs.Args() {
+				if err := pkg.InspectModel(f, os.Stdout); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	},
+	{
+		name:  "migrate-model",
+		usage: "rewrite model files in the current format",
+		run: func(fs *flag.FlagSet, args []string) error {
+			fs.Usage = func() {
+				fmt.Fprintln(fs.Output(), "usage: war migrate-model <file>...")
+			}
+			fs.Parse(args)
+
+			if fs.NArg() == 0 {
+				fs.Usage()
+				return flag.ErrHelp
+			}
+
+			for _, f := range fs.Args() {
+				m, err := pkg.LoadModel(f)
+				if err != nil {
+					return err
+				}
+
+				if err := pkg.WriteModel(m, f); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: war <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "run war <command> -h for the flags of a command")
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage()
+		return
+	}
+
+	for _, c := range commands {
+		if c.name != os.Args[1] {
+			continue
+		}
+
+		fs := flag.NewFlagSet("war "+c.name, flag.ExitOnError)
+		if err := c.run(fs, os.Args[2:]); err != nil {
+			if err == flag.ErrHelp {
+				os.Exit(2)
+			}
+
+			fmt.Fprintln(os.Stderr, "war "+c.name+":", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "war: unknown command", os.Args[1])
+	usage()
+	os.Exit(2)
+}
@@ -3,7 +3,6 @@ module github.com/iryoda/war
 go 1.23.3
 
 require (
-	github.com/asaskevich/EventBus v0.0.0-20200907212545-49d423059eef
 	github.com/gen2brain/raylib-go/raylib v0.0.0-20241111144450-60446bce159d
 	github.com/google/uuid v1.3.0
 )
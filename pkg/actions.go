@@ -0,0 +1,377 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type STANCE string
+
+const (
+	// Every unit goes for the enemy closest to it
+	STANCE_ADVANCE STANCE = "ADVANCE"
+	// Units only engage enemies within HOLD_RANGE
+	STANCE_HOLD STANCE = "HOLD"
+	// The army focuses the enemy closest to its base
+	STANCE_FOCUS_NEAREST STANCE = "FOCUS_NEAREST"
+	// The army focuses the enemy with the least hp
+	STANCE_FOCUS_WEAKEST STANCE = "FOCUS_WEAKEST"
+	// Units go back in front of their base, only hitting the enemies they
+	// are in contact with
+	STANCE_RETREAT STANCE = "RETREAT"
+)
+
+var STANCES = []STANCE{STANCE_ADVANCE, STANCE_HOLD, STANCE_FOCUS_NEAREST, STANCE_FOCUS_WEAKEST, STANCE_RETREAT}
+
+const HOLD_RANGE = 60
+
+// Distance from the base retreating units wait at
+const RETREAT_DISTANCE = 30
+
+// Spawn lanes split the front of the base in columns, from left to right
+const LANES = 3
+const LANE_WIDTH = 66
+const LANE_ANY = -1
+const LANE_SPAWN_ATTEMPTS = 20
+
+// Most units a single buy action can get
+const MAX_BUY_COUNT = 5
+
+var UNITY_NAMES = map[UNITY_TYPE]string{
+	SOLDIER: "SOLDIER",
+	BOMBER:  "BOMBER",
+}
+
+// Prefixes of the actions that carry arguments
+const BUY_PREFIX = "BUY"
+const STANCE_PREFIX = "STANCE"
+
+// Command is an action with its arguments, see BuyAction and StanceAction.
+type Command struct {
+	Name   string
+	Unity  UNITY_TYPE
+	Count  int
+	Lane   int
+	Stance STANCE
+}
+
+// BuyAction buys count units of a type in a lane, or LANE_ANY. A single
+// soldier anywhere is plain BUY_SOLDIER and a single bomber BUY_BOMBER.
+func BuyAction(t UNITY_TYPE, count int, lane int) ACTION {
+	if count == 1 && lane == LANE_ANY {
+		switch t {
+		case SOLDIER:
+			return BUY_SOLDIER
+		case BOMBER:
+			return BUY_BOMBER
+		}
+	}
+
+	return ACTION(fmt.Sprint(BUY_PREFIX, ":", UNITY_NAMES[t], ":", count, ":", lane))
+}
+
+func StanceAction(s STANCE) ACTION {
+	return ACTION(fmt.Sprint(STANCE_PREFIX, ":", s))
+}
+
+// ParseAction reads an action made by BuyAction or StanceAction.
+func ParseAction(a ACTION) (Command, error) {
+	parts := strings.Split(string(a), ":")
+	c := Command{Name: parts[0]}
+
+	switch {
+	case c.Name == BUY_PREFIX && len(parts) == 4:
+		found := false
+		for t, name := range UNITY_NAMES {
+			if name == parts[1] {
+				c.Unity = t
+				found = true
+			}
+		}
+		if !found {
+			return c, errors.New("Invalid Unity Type")
+		}
+
+		count, err := strconv.Atoi(parts[2])
+		if err != nil || count < 1 || count > MAX_BUY_COUNT {
+			return c, fmt.Errorf("Invalid count %q", parts[2])
+		}
+		c.Count = count
+
+		lane, err := strconv.Atoi(parts[3])
+		if err != nil || lane < LANE_ANY || lane >= LANES {
+			return c, fmt.Errorf("Invalid lane %q", parts[3])
+		}
+		c.Lane = lane
+
+		return c, nil
+	case c.Name == STANCE_PREFIX && len(parts) == 2:
+		c.Stance = STANCE(parts[1])
+		if !slices.Contains(STANCES, c.Stance) {
+			return c, fmt.Errorf("Invalid stance %q", parts[1])
+		}
+
+		return c, nil
+	}
+
+	return c, errors.New("Invalid Action")
+}
+
+func (g *Game) handleCommand(e ActionEvent) error {
+	c, err := ParseAction(e.Action)
+	if err != nil {
+		return err
+	}
+
+	switch c.Name {
+	case BUY_PREFIX:
+		return g.BuyUnities(c.Unity, e.Owner, c.Count, c.Lane)
+	case STANCE_PREFIX:
+		g.GetPlayer(e.Owner).Stance = c.Stance
+	}
+
+	return nil
+}
+
+// BuyUnities buys up to count units, as many as the coins pay for. It only
+// fails when not even one could be bought.
+func (g *Game) BuyUnities(t UNITY_TYPE, id PLAYER_TYPE, count int, lane int) error {
+	p := g.GetPlayer(id)
+
+	for i := range count {
+		cost := g.GetCurrentUnityCost(t)
+		if p.Coins < cost {
+			if i == 0 {
+				return errors.New("Not enough coins")
+			}
+			return nil
+		}
+
+		// Paid before it spawns, as in BuyUnity, so UnitySpawnedEvent
+		// subscribers see the coins left
+		p.Coins -= cost
+		if err := g.addUnity(t, id, lane); err != nil {
+			p.Coins += cost
+			if i == 0 {
+				return err
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (p Player) GetStance() STANCE {
+	if p.Stance == "" {
+		return STANCE_ADVANCE
+	}
+	return p.Stance
+}
+
+// getNewUnityPositionInLane finds a free spot in a lane in front of the
+// base, failing when none is found after a few tries.
+func getNewUnityPositionInLane(id PLAYER_TYPE, game Game, uType UNITY_TYPE, lane int) (Position, error) {
+	bP := game.GetBasePosition(id)
+	unities := game.GetUnitiesByPlayerId(id)
+	t := UNITY_THICK[uType]
+
+	for range LANE_SPAWN_ATTEMPTS {
+		dy := 10 + game.rng.IntN(100) + BASE_THICKNESS
+		if id == RED {
+			dy = -dy
+		}
+
+		pos := Position{
+			X: bP.X + (lane-LANES/2)*LANE_WIDTH - LANE_WIDTH/2 + game.rng.IntN(LANE_WIDTH),
+			Y: bP.Y + dy,
+		}
+
+		rec := NewCollisionBox(float32(pos.X)-float32(t/2), float32(pos.Y)-float32(t/2), float32(t), float32(t))
+
+		free := true
+		for _, u := range unities {
+			if rec.Collides(u.GetCollisionBox()) {
+				free = false
+				break
+			}
+		}
+
+		if free {
+			return pos, nil
+		}
+	}
+
+	return Position{}, errors.New("Lane is full")
+}
+
+// FindStanceTarget picks the enemy a unit goes for following the stance of
+// its owner, an empty Unity when there is none.
+func (g *Game) FindStanceTarget(u Unity) Unity {
+	switch g.GetPlayerById(u.PlayerOwner).GetStance() {
+	case STANCE_HOLD:
+		closest := FindClosestEnemyUnity(u, g)
+		if closest.Id != 0 && positionDistance(u.Position, closest.Position) > HOLD_RANGE {
+			return Unity{}
+		}
+		return closest
+	case STANCE_FOCUS_NEAREST:
+		base := g.GetBasePosition(u.PlayerOwner)
+		return g.findEnemy(u.PlayerOwner, func(a, b Unity) bool {
+			return positionDistance(base, a.Position) < positionDistance(base, b.Position)
+		})
+	case STANCE_FOCUS_WEAKEST:
+		return g.findEnemy(u.PlayerOwner, func(a, b Unity) bool {
+			if hpA, hpB := g.RemainingHp(a), g.RemainingHp(b); hpA != hpB {
+				return hpA < hpB
+			}
+			return positionDistance(u.Position, a.Position) < positionDistance(u.Position, b.Position)
+		})
+	}
+
+	return FindClosestEnemyUnity(u, g)
+}
+
+// findEnemy returns the alive enemy of owner that comes first by less.
+func (g *Game) findEnemy(owner PLAYER_TYPE, less func(a, b Unity) bool) Unity {
+	var best Unity
+	for _, e := range g.Unities {
+		if e.State == DEAD || e.PlayerOwner == owner {
+			continue
+		}
+		if best.Id == 0 || less(e, best) {
+			best = e
+		}
+	}
+
+	return best
+}
+
+// Outputs a model can write besides OUTPUT_NAMES. buy_count and lane are
+// read as a share of MAX_BUY_COUNT and LANES, the stance with the highest
+// value over 0.5 is taken.
+var ACTION_OUTPUTS = []string{
+	"buy_bomber",
+	"buy_count",
+	"lane",
+	"stance_advance",
+	"stance_hold",
+	"stance_focus_nearest",
+	"stance_focus_weakest",
+	"stance_retreat",
+}
+
+// Every output new models can write
+var FULL_OUTPUTS = append(slices.Clone(OUTPUT_NAMES), ACTION_OUTPUTS...)
+
+var STANCE_OUTPUTS = map[string]STANCE{
+	"stance_advance":       STANCE_ADVANCE,
+	"stance_hold":          STANCE_HOLD,
+	"stance_focus_nearest": STANCE_FOCUS_NEAREST,
+	"stance_focus_weakest": STANCE_FOCUS_WEAKEST,
+	"stance_retreat":       STANCE_RETREAT,
+}
+
+// Action presets
+const ACTIONS_DEFAULT = "default"
+const ACTIONS_FULL = "full"
+
+// Decide turns the named outputs of a model into actions. e is the update
+// the model answered, so a stance already held is not sent again.
+func Decide(names []string, out Output, e GameUpdateEvent) []ACTION {
+	res := []ACTION{}
+
+	v := map[string]float64{}
+	for i, name := range names {
+		if i < len(out) {
+			v[name] = out[i]
+		}
+	}
+
+	count := 1
+	if c, ok := v["buy_count"]; ok {
+		count = 1 + int(math.Round(min(max(c, 0), 1)*(MAX_BUY_COUNT-1)))
+	}
+
+	lane := LANE_ANY
+	if l, ok := v["lane"]; ok {
+		lane = min(max(int(l*LANES), 0), LANES-1)
+	}
+
+	if step(v["upgrade_tech"]) == 1 {
+		res = append(res, UPDATE_TECH)
+	}
+
+	if step(v["upgrade_mining"]) == 1 {
+		res = append(res, UPDATE_MINING)
+	}
+
+	if step(v["buy_soldier"]) == 1 {
+		res = append(res, BuyAction(SOLDIER, count, lane))
+	}
+
+	if step(v["buy_bomber"]) == 1 {
+		res = append(res, BuyAction(BOMBER, count, lane))
+	}
+
+	best := ""
+	for _, name := range names {
+		if _, ok := STANCE_OUTPUTS[name]; ok && v[name] > 0.5 && (best == "" || v[name] > v[best]) {
+			best = name
+		}
+	}
+	if best != "" && STANCE_OUTPUTS[best] != e.Stance {
+		res = append(res, StanceAction(STANCE_OUTPUTS[best]))
+	}
+
+	return res
+}
+
+// ParseOutputs reads a preset name or a comma separated list of outputs.
+func ParseOutputs(s string) ([]string, error) {
+	switch s {
+	case ACTIONS_DEFAULT:
+		return slices.Clone(OUTPUT_NAMES), nil
+	case ACTIONS_FULL:
+		return slices.Clone(FULL_OUTPUTS), nil
+	}
+
+	res := []string{}
+	for _, name := range strings.Split(s, ",") {
+		res = append(res, strings.TrimSpace(name))
+	}
+
+	return res, ValidateOutputs(res)
+}
+
+func ValidateOutputs(names []string) error {
+	if len(names) == 0 {
+		return errors.New("model has no outputs")
+	}
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !slices.Contains(FULL_OUTPUTS, name) {
+			return fmt.Errorf("unknown output %q, expected one of %v", name, OutputNames())
+		}
+		if seen[name] {
+			return fmt.Errorf("output %q is written twice", name)
+		}
+		seen[name] = true
+	}
+
+	return nil
+}
+
+// OutputNames lists every output, sorted.
+func OutputNames() []string {
+	res := slices.Clone(FULL_OUTPUTS)
+	sort.Strings(res)
+
+	return res
+}
@@ -0,0 +1,141 @@
+package pkg
+
+import (
+	"math/rand/v2"
+)
+
+// Agent controls one player of a game. Act receives the player's view of
+// the game every game second and returns the actions to take.
+type Agent interface {
+	Act(e GameUpdateEvent) []ACTION
+}
+
+// Poller is implemented by agents that read input, Poll is called once per
+// rendered frame so no key press is lost between two updates.
+type Poller interface {
+	Poll()
+}
+
+// Rejecter is implemented by agents that want to know when one of their
+// actions was not taken, e.g. not allowed by the war rules.
+type Rejecter interface {
+	Rejected(action ACTION, err error)
+}
+
+// RunMatch plays the game with one agent per player until there is a
+// winner. r may be nil to run it headless, players without an agent do
+// nothing.
+func RunMatch(g *Game, agents map[PLAYER_TYPE]Agent, r Renderer) PLAYER_TYPE {
+	pollers := AttachAgents(g, agents)
+
+	if r != nil && len(pollers) > 0 {
+		r = pollingRenderer{Renderer: r, pollers: pollers}
+	}
+
+	return RunWithRenderer(g, r)
+}
+
+// AttachAgents subscribes each agent to the updates of its player and
+// returns the ones reading input. Agents implementing Rejecter are told
+// about the actions that were not taken.
+func AttachAgents(g *Game, agents map[PLAYER_TYPE]Agent) []Poller {
+	pollers := []Poller{}
+
+	for _, p := range []PLAYER_TYPE{BLUE, RED} {
+		a := agents[p]
+		if a == nil {
+			continue
+		}
+
+		g.OnUpdate(func(e GameUpdateEvent) {
+			if e.Owner != p {
+				return
+			}
+
+			rejecter, _ := a.(Rejecter)
+			for _, action := range a.Act(e) {
+				err := g.HandleActionEvent(ActionEvent{
+					Owner:  p,
+					Action: action,
+				})
+				if err != nil && rejecter != nil {
+					rejecter.Rejected(action, err)
+				}
+			}
+		})
+
+		if poller, ok := a.(Poller); ok {
+			pollers = append(pollers, poller)
+		}
+	}
+
+	return pollers
+}
+
+type pollingRenderer struct {
+	Renderer
+	pollers []Poller
+}
+
+func (r pollingRenderer) Frame(g *Game) {
+	for _, p := range r.pollers {
+		p.Poll()
+	}
+
+	r.Renderer.Frame(g)
+}
+
+func (m *Model) Act(e GameUpdateEvent) []ACTION {
+	return m.HandleUpdate(e)
+}
+
+type ScriptStep struct {
+	// Game second the action is taken at
+	Time   int
+	Action ACTION
+}
+
+// ScriptedAgent follows a fixed build order, every step is taken on the
+// first update at or after its time.
+type ScriptedAgent struct {
+	Script []ScriptStep
+	next   int
+}
+
+func NewScriptedAgent(script []ScriptStep) *ScriptedAgent {
+	return &ScriptedAgent{Script: script}
+}
+
+func (s *ScriptedAgent) Act(e GameUpdateEvent) []ACTION {
+	res := []ACTION{}
+
+	for s.next < len(s.Script) && s.Script[s.next].Time <= e.Time {
+		res = append(res, s.Script[s.next].Action)
+		s.next++
+	}
+
+	return res
+}
+
+// RandomAgent takes every action with the same chance on each update, a
+// baseline to measure models against.
+type RandomAgent struct {
+	Rate float64
+	rng  *rand.Rand
+}
+
+func NewRandomAgent(rate float64, seed uint64) *RandomAgent {
+	return &RandomAgent{Rate: rate, rng: NewRand(seed)}
+}
+
+func (r *RandomAgent) Act(e GameUpdateEvent) []ACTION {
+	res := []ACTION{}
+
+	for _, a := range []ACTION{UPDATE_TECH, UPDATE_MINING, BUY_SOLDIER} {
+		if r.rng.Float64() < r.Rate {
+			res = append(res, a)
+		}
+	}
+
+	return res
+}
@@ -1,108 +1,147 @@
 package pkg
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"os"
 )
 
-func Run() {
-	g := NewGame(CreateGameArgs{Speed: 1})
-	g.Init()
-
-	RunGame(&g)
+// MatchConfig describes a single game started from the command line.
+type MatchConfig struct {
+	// Model files playing each side, unused for human players
+	BlueModel string
+	RedModel  string
+	// Game seed, a random one is picked when zero
+	Seed  uint64
+	Speed int
+	// Play without a window
+	Headless bool
+	// File the replay of the game is written to, nothing is recorded when
+	// empty
+	Record string
+	// Snapshot the game starts from instead of a new one
+	Load string
+	// File the game is saved to when pressing F5
+	Save string
+	// Actions allowed once the border is down, a loaded game keeps its own
+	WarRules WarRules
 }
 
-func AiXAi() {
-	f, err := os.Open("best.json")
+func Run(cfg MatchConfig) error {
+	kB, err := NewKeyboardAgent(BLUE)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer f.Close()
 
-	b, err := io.ReadAll(f)
+	kR, err := NewKeyboardAgent(RED)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	var m Model
-	err = json.Unmarshal(b, &m)
+	g, err := newMatchGame(cfg)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	mB, _ := m.Copy()
-	mR, _ := m.Copy()
+	return playMatch(cfg, &g, map[PLAYER_TYPE]Agent{
+		BLUE: kB,
+		RED:  kR,
+	})
+}
 
-	mB.Type = BLUE
-	mR.Type = RED
+func AiXAi(cfg MatchConfig) error {
+	mB, err := loadPlayerModel(cfg.BlueModel, BLUE)
+	if err != nil {
+		return err
+	}
 
-	g := NewGame(CreateGameArgs{
-		Speed: 32,
-	})
-	g.Init()
+	mR, err := loadPlayerModel(cfg.RedModel, RED)
+	if err != nil {
+		return err
+	}
 
-	// Subs to Event
-	Bus.Subscribe(fmt.Sprint("game:", g.ID, "/update"), func(e GameUpdateEvent) {
-		actions := mB.HandleUpdate(e)
-		for _, a := range actions {
-			g.HandleActionEvent(ActionEvent{
-				Owner:  BLUE,
-				Action: a,
-			})
-		}
-	})
-	Bus.Subscribe(fmt.Sprint("game:", g.ID, "/update"), func(e GameUpdateEvent) {
-		actions := mR.HandleUpdate(e)
-		for _, a := range actions {
-			g.HandleActionEvent(ActionEvent{
-				Owner:  RED,
-				Action: a,
-			})
-		}
-	})
+	g, err := newMatchGame(cfg)
+	if err != nil {
+		return err
+	}
 
-	RunGame(&g)
+	return playMatch(cfg, &g, map[PLAYER_TYPE]Agent{
+		BLUE: mB,
+		RED:  mR,
+	})
 }
 
-func PalyerXAi() {
-	f, err := os.Open("best.json")
+func PalyerXAi(cfg MatchConfig) error {
+	kB, err := NewKeyboardAgent(BLUE)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer f.Close()
 
-	b, err := io.ReadAll(f)
+	mR, err := loadPlayerModel(cfg.RedModel, RED)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	var m Model
-	err = json.Unmarshal(b, &m)
+	g, err := newMatchGame(cfg)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	mR, _ := m.Copy()
+	return playMatch(cfg, &g, map[PLAYER_TYPE]Agent{
+		BLUE: kB,
+		RED:  mR,
+	})
+}
 
-	mR.Type = RED
+func playMatch(cfg MatchConfig, g *Game, agents map[PLAYER_TYPE]Agent) error {
+	var replay *Replay
+	if cfg.Record != "" {
+		replay = g.Record()
+	}
 
-	g := NewGame(CreateGameArgs{
-		Speed: 1,
-	})
-	g.Init()
+	var winner PLAYER_TYPE
+	if cfg.Headless {
+		winner = RunMatch(g, agents, nil)
+	} else {
+		winner = RunGame(g, agents, cfg.Save)
+	}
+	fmt.Println("WINNER:", winner, "SEED:", g.Seed)
+
+	if replay == nil {
+		return nil
+	}
 
-	// Subs to Event
-	Bus.Subscribe(fmt.Sprint("game:", g.ID, "/update"), func(e GameUpdateEvent) {
-		actions := mR.HandleUpdate(e)
-		for _, a := range actions {
-			g.HandleActionEvent(ActionEvent{
-				Owner:  RED,
-				Action: a,
-			})
+	// The window was closed before the end, the replay could not be verified
+	if g.Winner == "" {
+		fmt.Println("REPLAY NOT SAVED: the match did not finish")
+		return nil
+	}
+
+	return WriteReplay(replay, cfg.Record)
+}
+
+// newMatchGame starts a new game or restores the one to load.
+func newMatchGame(cfg MatchConfig) (Game, error) {
+	if cfg.Load != "" {
+		g, err := LoadGame(cfg.Load)
+		if err != nil {
+			return g, err
 		}
-	})
 
-	RunGame(&g)
+		g.Speed = cfg.Speed
+		return g, nil
+	}
+
+	g := NewGame(CreateGameArgs{Speed: cfg.Speed, Seed: cfg.Seed, WarRules: cfg.WarRules})
+	g.Init()
+
+	return g, nil
+}
+
+func loadPlayerModel(file string, p PLAYER_TYPE) (*Model, error) {
+	m, err := LoadModel(file)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Type = p
+	return &m, nil
 }
@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const CHECKPOINT_VERSION = 1
+const CHECKPOINT_FILE = "checkpoint.json"
+
+// GenerationStats is the fitness spread of a finished generation.
+type GenerationStats struct {
+	Generation int
+	Best       int
+	Mean       int
+	Worst      int
+	Sigma      float64
+}
+
+// Checkpoint is everything RunTrain needs to go on from the start of a
+// generation as if it had never stopped.
+type Checkpoint struct {
+	Version int
+	Config  TrainConfig
+	// Next generation to play
+	Generation int
+	Population []Individual
+	// Best individual of every generation played so far
+	Best    Individual
+	Sigma   float64
+	History []GenerationStats
+	// State of the training random source
+	Rng []byte
+}
+
+// WriteCheckpoint writes to a temporary file first so an interrupted write
+// never leaves a broken checkpoint behind.
+func WriteCheckpoint(c Checkpoint, file string) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+func LoadCheckpoint(file string) (Checkpoint, error) {
+	c := Checkpoint{}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return c, err
+	}
+
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("%s: %w", file, err)
+	}
+
+	if c.Version != CHECKPOINT_VERSION {
+		return c, fmt.Errorf("%s: unsupported checkpoint version %d", file, c.Version)
+	}
+
+	return c, nil
+}
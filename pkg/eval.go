@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const EVAL_GAMES = 10
+
+// EvalConfig plays two models against each other headless.
+type EvalConfig struct {
+	BlueModel string
+	RedModel  string
+	Games     int
+	// Seed the game seeds are drawn from, a random one is picked when zero
+	Seed    uint64
+	Workers int
+	// Ladder file updated with every game, off when empty
+	Ladder string
+	// Actions allowed once the border is down
+	WarRules WarRules
+}
+
+type EvalResult struct {
+	BlueWins int
+	RedWins  int
+	Draws    int
+	Trains   []Train
+}
+
+func Eval(cfg EvalConfig) (EvalResult, error) {
+	if cfg.Seed == 0 {
+		cfg.Seed = NewSeed()
+	}
+	if cfg.Games <= 0 {
+		cfg.Games = EVAL_GAMES
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	fmt.Println("EVAL SEED:", cfg.Seed)
+
+	blue, err := LoadModel(cfg.BlueModel)
+	if err != nil {
+		return EvalResult{}, err
+	}
+
+	red, err := LoadModel(cfg.RedModel)
+	if err != nil {
+		return EvalResult{}, err
+	}
+
+	rng := NewRand(cfg.Seed)
+	res := EvalResult{}
+
+	for i := range cfg.Games {
+		g := NewGame(CreateGameArgs{Speed: 32, Seed: rng.Uint64(), WarRules: cfg.WarRules})
+		g.Init()
+
+		mB, _ := blue.Copy()
+		mB.Type = BLUE
+		mR, _ := red.Copy()
+		mR.Type = RED
+
+		res.Trains = append(res.Trains, Train{
+			Id:        i,
+			Game:      &g,
+			ModelBlue: mB,
+			ModelRed:  mR,
+			Seed:      g.Seed,
+		})
+	}
+
+	PlayTrains(res.Trains, cfg.Workers)
+
+	for i, t := range res.Trains {
+		switch {
+		case t.Game.Draw:
+			res.Draws++
+		case t.Winner == BLUE:
+			res.BlueWins++
+		default:
+			res.RedWins++
+		}
+
+		fmt.Println("EVAL GAME:", i, "WINNER:", t.Winner, "DRAW:", t.Game.Draw, "SEED:", t.Seed, "POINTS:", t.WinnerPoints)
+	}
+
+	if cfg.Ladder != "" {
+		err := UpdateLadder(cfg.Ladder, func(l *Ladder) error {
+			for _, file := range []string{cfg.BlueModel, cfg.RedModel} {
+				if err := l.Track(file); err != nil {
+					return err
+				}
+			}
+
+			for _, t := range res.Trains {
+				l.RecordTrain(cfg.BlueModel, cfg.RedModel, t)
+			}
+			return nil
+		})
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// InspectModel writes the layers of a model file and their weights range.
+func InspectModel(file string, w io.Writer) error {
+	m, err := LoadModel(file)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "File:", file)
+	fmt.Fprintln(w, "Type:", m.Type)
+	fmt.Fprintln(w, "Points:", m.Points)
+	fmt.Fprintln(w, "Version:", m.Version)
+	if p := m.Provenance; p != nil {
+		fmt.Fprintln(w, "Trained:", p.Date.Format(time.RFC3339), "SEED:", p.Seed, "GEN:", p.Generation, "FITNESS:", p.Fitness, p.Points)
+	}
+	fmt.Fprintln(w, "Inputs:", strings.Join(m.Inputs, ", "))
+	fmt.Fprintln(w, "Outputs:", strings.Join(m.Outputs, ", "))
+
+	fmt.Fprintln(w, "Topology:", FormatTopology(m.Topology()))
+
+	for i, l := range m.Layers {
+		inputs := 0
+		if len(l.Weights) > 0 {
+			inputs = len(l.Weights[0])
+		}
+
+		name := fmt.Sprint("Layer ", i+1, " (", l.Activation, ")")
+		min, max, mean := weightsRange(l.Weights)
+		fmt.Fprintf(w, "%-22s %3d x %-3d bias %-3d weights min %.3f max %.3f mean %.3f\n",
+			name, len(l.Weights), inputs, len(l.Bias), min, max, mean)
+	}
+
+	return nil
+}
+
+func weightsRange(weights [][]float64) (float64, float64, float64) {
+	min, max, sum, n := math.Inf(1), math.Inf(-1), 0.0, 0
+
+	for _, row := range weights {
+		for _, v := range row {
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+			sum += v
+			n++
+		}
+	}
+
+	if n == 0 {
+		return 0, 0, 0
+	}
+
+	return min, max, sum / float64(n)
+}
@@ -0,0 +1,331 @@
+package pkg
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+)
+
+// Topics published on the bus of every game, each one carries a single
+// event type:
+//
+//	UPDATE_TOPIC          GameUpdateEvent, every game second for each player
+//	UNITY_SPAWNED_TOPIC   UnitySpawnedEvent, a unit was bought
+//	UNITY_MOVED_TOPIC     UnityMovedEvent, a unit changed position in a tick
+//	COMBAT_STARTED_TOPIC  CombatStartedEvent, a unit reached its target
+//	DAMAGE_TOPIC          DamageEvent, a unit hit another one
+//	UNITY_DIED_TOPIC      UnityDiedEvent, a unit was killed
+//	UPGRADE_TOPIC         UpgradeEvent, a player upgraded tech or mining
+//	BORDER_DROPPED_TOPIC  BorderDroppedEvent, the war phase started
+//	TIMEOUT_TOPIC         TimeoutEvent, the war took too long
+//	GAME_OVER_TOPIC       GameOverEvent, the game has a winner
+//	ACTION_REJECTED_TOPIC ActionRejectedEvent, an action was not taken
+const (
+	UPDATE_TOPIC          = "update"
+	UNITY_SPAWNED_TOPIC   = "unity/spawned"
+	UNITY_MOVED_TOPIC     = "unity/moved"
+	COMBAT_STARTED_TOPIC  = "unity/combat"
+	DAMAGE_TOPIC          = "unity/damage"
+	UNITY_DIED_TOPIC      = "unity/died"
+	UPGRADE_TOPIC         = "player/upgrade"
+	BORDER_DROPPED_TOPIC  = "game/border"
+	TIMEOUT_TOPIC         = "game/timeout"
+	GAME_OVER_TOPIC       = "game/over"
+	ACTION_REJECTED_TOPIC = "player/rejected"
+)
+
+type UnitySpawnedEvent struct {
+	GameID uuid.UUID
+	Tick   int
+	Unity  Unity
+}
+
+type UnityMovedEvent struct {
+	GameID  uuid.UUID
+	Tick    int
+	UnityId int
+	Owner   PLAYER_TYPE
+	From    Position
+	To      Position
+}
+
+type CombatStartedEvent struct {
+	GameID     uuid.UUID
+	Tick       int
+	AttackerId int
+	TargetId   int
+	Owner      PLAYER_TYPE
+}
+
+type DamageEvent struct {
+	GameID     uuid.UUID
+	Tick       int
+	AttackerId int
+	TargetId   int
+	Damage     int
+	// Hp left on the target after the hit
+	Hp int
+}
+
+type UnityDiedEvent struct {
+	GameID   uuid.UUID
+	Tick     int
+	UnityId  int
+	Owner    PLAYER_TYPE
+	KillerId int
+}
+
+type UpgradeEvent struct {
+	GameID uuid.UUID
+	Tick   int
+	Owner  PLAYER_TYPE
+	// UPDATE_TECH or UPDATE_MINING
+	Upgrade ACTION
+	Level   int
+	Cost    int
+}
+
+type BorderDroppedEvent struct {
+	GameID      uuid.UUID
+	Tick        int
+	DisplayTime int
+}
+
+type TimeoutEvent struct {
+	GameID      uuid.UUID
+	Tick        int
+	BlueUnities int
+	RedUnities  int
+}
+
+type GameOverEvent struct {
+	GameID uuid.UUID
+	Tick   int
+	Winner PLAYER_TYPE
+	// Set when both sides ended with as many units, Winner is then BLUE
+	Draw bool
+	Seed uint64
+}
+
+type ActionRejectedEvent struct {
+	GameID uuid.UUID
+	Tick   int
+	Owner  PLAYER_TYPE
+	Action ACTION
+	// Why the action was not taken
+	Error error
+}
+
+// Event type carried by each topic
+var TOPIC_EVENTS = map[string]reflect.Type{
+	UPDATE_TOPIC:          reflect.TypeOf(GameUpdateEvent{}),
+	UNITY_SPAWNED_TOPIC:   reflect.TypeOf(UnitySpawnedEvent{}),
+	UNITY_MOVED_TOPIC:     reflect.TypeOf(UnityMovedEvent{}),
+	COMBAT_STARTED_TOPIC:  reflect.TypeOf(CombatStartedEvent{}),
+	DAMAGE_TOPIC:          reflect.TypeOf(DamageEvent{}),
+	UNITY_DIED_TOPIC:      reflect.TypeOf(UnityDiedEvent{}),
+	UPGRADE_TOPIC:         reflect.TypeOf(UpgradeEvent{}),
+	BORDER_DROPPED_TOPIC:  reflect.TypeOf(BorderDroppedEvent{}),
+	TIMEOUT_TOPIC:         reflect.TypeOf(TimeoutEvent{}),
+	GAME_OVER_TOPIC:       reflect.TypeOf(GameOverEvent{}),
+	ACTION_REJECTED_TOPIC: reflect.TypeOf(ActionRejectedEvent{}),
+}
+
+// eventBus holds the subscriptions of a game by topic, in the order they
+// were made. Each one has its own id, so two handlers made from the same
+// function are still told apart.
+type eventBus struct {
+	next     int
+	handlers map[string][]*Subscription
+
+	queue      []queuedEvent
+	publishing bool
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{handlers: map[string][]*Subscription{}}
+}
+
+// Subscription is a handler attached to a game's bus. All of them are
+// released when the game finishes.
+type Subscription struct {
+	id      int
+	bus     *eventBus
+	topic   string
+	handler reflect.Value
+}
+
+// Unsubscribe releases the handler, it can be called from inside any
+// handler, the released one included.
+func (s *Subscription) Unsubscribe() {
+	if s.bus == nil {
+		return
+	}
+
+	handlers := s.bus.handlers[s.topic]
+	for i, h := range handlers {
+		if h.id == s.id {
+			s.bus.handlers[s.topic] = append(handlers[:i:i], handlers[i+1:]...)
+			break
+		}
+	}
+
+	s.bus = nil
+}
+
+// Subscribe attaches fn to a topic of this game only, fn must take the
+// event type of the topic, see TOPIC_EVENTS.
+func (g *Game) Subscribe(topic string, fn any) (*Subscription, error) {
+	t, ok := TOPIC_EVENTS[topic]
+	if !ok {
+		return nil, fmt.Errorf("unknown topic %q", topic)
+	}
+
+	h := reflect.ValueOf(fn)
+	if h.Kind() != reflect.Func || h.Type().NumIn() != 1 || !t.AssignableTo(h.Type().In(0)) {
+		return nil, fmt.Errorf("handler of %q must be a func(%s)", topic, t.Name())
+	}
+
+	g.events.next++
+	s := &Subscription{id: g.events.next, bus: g.events, topic: topic, handler: h}
+	g.events.handlers[topic] = append(g.events.handlers[topic], s)
+	g.subscriptions = append(g.subscriptions, s)
+
+	return s, nil
+}
+
+// OnUpdate calls fn with the state of each player every game second.
+func (g *Game) OnUpdate(fn func(GameUpdateEvent)) *Subscription {
+	s, _ := g.Subscribe(UPDATE_TOPIC, fn)
+	return s
+}
+
+func (g *Game) OnUnitySpawned(fn func(UnitySpawnedEvent)) *Subscription {
+	s, _ := g.Subscribe(UNITY_SPAWNED_TOPIC, fn)
+	return s
+}
+
+func (g *Game) OnUnityMoved(fn func(UnityMovedEvent)) *Subscription {
+	s, _ := g.Subscribe(UNITY_MOVED_TOPIC, fn)
+	return s
+}
+
+func (g *Game) OnCombatStarted(fn func(CombatStartedEvent)) *Subscription {
+	s, _ := g.Subscribe(COMBAT_STARTED_TOPIC, fn)
+	return s
+}
+
+func (g *Game) OnDamage(fn func(DamageEvent)) *Subscription {
+	s, _ := g.Subscribe(DAMAGE_TOPIC, fn)
+	return s
+}
+
+func (g *Game) OnUnityDied(fn func(UnityDiedEvent)) *Subscription {
+	s, _ := g.Subscribe(UNITY_DIED_TOPIC, fn)
+	return s
+}
+
+func (g *Game) OnUpgrade(fn func(UpgradeEvent)) *Subscription {
+	s, _ := g.Subscribe(UPGRADE_TOPIC, fn)
+	return s
+}
+
+func (g *Game) OnBorderDropped(fn func(BorderDroppedEvent)) *Subscription {
+	s, _ := g.Subscribe(BORDER_DROPPED_TOPIC, fn)
+	return s
+}
+
+func (g *Game) OnTimeout(fn func(TimeoutEvent)) *Subscription {
+	s, _ := g.Subscribe(TIMEOUT_TOPIC, fn)
+	return s
+}
+
+func (g *Game) OnGameOver(fn func(GameOverEvent)) *Subscription {
+	s, _ := g.Subscribe(GAME_OVER_TOPIC, fn)
+	return s
+}
+
+func (g *Game) OnActionRejected(fn func(ActionRejectedEvent)) *Subscription {
+	s, _ := g.Subscribe(ACTION_REJECTED_TOPIC, fn)
+	return s
+}
+
+type queuedEvent struct {
+	topic string
+	event any
+}
+
+// publish delivers e to the subscribers of topic. Events published from
+// inside a handler (an agent buying a unit on update) are queued and
+// delivered right after it returns, so every subscriber sees them in the
+// order they happened.
+func (g *Game) publish(topic string, e any) {
+	bus := g.events
+	bus.queue = append(bus.queue, queuedEvent{topic: topic, event: e})
+	if bus.publishing {
+		return
+	}
+
+	bus.publishing = true
+	for len(bus.queue) > 0 {
+		next := bus.queue[0]
+		bus.queue = bus.queue[1:]
+
+		// Handlers subscribed by a handler get the next events, released
+		// ones are skipped right away
+		args := []reflect.Value{reflect.ValueOf(next.event)}
+		for _, s := range bus.handlers[next.topic] {
+			if s.bus != nil {
+				s.handler.Call(args)
+			}
+		}
+	}
+	bus.publishing = false
+}
+
+func (g *Game) publishUpgrade(id PLAYER_TYPE, upgrade ACTION, level int, cost int) {
+	g.publish(UPGRADE_TOPIC, UpgradeEvent{
+		GameID:  g.ID,
+		Tick:    g.Tick,
+		Owner:   id,
+		Upgrade: upgrade,
+		Level:   level,
+		Cost:    cost,
+	})
+}
+
+// publishMoves sends a UnityMovedEvent for every unit whose position is not
+// the one it had at the start of the tick.
+func (g *Game) publishMoves(before []Position) {
+	for i, from := range before {
+		u := g.Unities[i]
+		if u.Position == from {
+			continue
+		}
+
+		g.publish(UNITY_MOVED_TOPIC, UnityMovedEvent{
+			GameID:  g.ID,
+			Tick:    g.Tick,
+			UnityId: u.Id,
+			Owner:   u.PlayerOwner,
+			From:    from,
+			To:      u.Position,
+		})
+	}
+}
+
+// Finish marks the game as over and releases every subscription.
+func (g *Game) Finish() {
+	for _, s := range g.subscriptions {
+		s.Unsubscribe()
+	}
+
+	g.subscriptions = nil
+	g.Finished = true
+
+	if g.recording != nil {
+		g.recording.Winner = g.Winner
+		g.recording.Ticks = g.Tick
+	}
+}
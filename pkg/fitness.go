@@ -0,0 +1,108 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Fitness strategies
+const FITNESS_POINTS = "points"
+const FITNESS_WIN_RATE = "win-rate"
+const FITNESS_MARGIN = "margin"
+const FITNESS_ECONOMY = "economy"
+const FITNESS_SPEED = "speed"
+
+// Fitness scores how well a player did in a finished game, the higher the
+// better. Both sides are scored, winner or not.
+type Fitness interface {
+	Score(g *Game, id PLAYER_TYPE) int
+}
+
+// FitnessFunc lets a plain function be used as a Fitness.
+type FitnessFunc func(g *Game, id PLAYER_TYPE) int
+
+func (f FitnessFunc) Score(g *Game, id PLAYER_TYPE) int {
+	return f(g, id)
+}
+
+var FITNESS = map[string]Fitness{
+	FITNESS_POINTS:   FitnessFunc(pointsFitness),
+	FITNESS_WIN_RATE: FitnessFunc(winRateFitness),
+	FITNESS_MARGIN:   FitnessFunc(marginFitness),
+	FITNESS_ECONOMY:  FitnessFunc(economyFitness),
+	FITNESS_SPEED:    FitnessFunc(speedFitness),
+}
+
+// GetFitness returns the built in fitness with that name.
+func GetFitness(name string) (Fitness, error) {
+	f, ok := FITNESS[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown fitness %q, expected one of %v", name, FitnessNames())
+	}
+
+	return f, nil
+}
+
+// FitnessNames lists the built in fitness names, sorted.
+func FitnessNames() []string {
+	res := []string{}
+	for name := range FITNESS {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// Units, levels and coins spent, see PlayerPoints
+func pointsFitness(g *Game, id PLAYER_TYPE) int {
+	return g.PlayerPoints(id)
+}
+
+// 1000 for a win, 500 for a draw, so the average is the win rate per mille
+func winRateFitness(g *Game, id PLAYER_TYPE) int {
+	if g.Draw {
+		return 500
+	}
+	if g.Winner == id {
+		return 1000
+	}
+	return 0
+}
+
+// Alive units left over the enemy ones
+func marginFitness(g *Game, id PLAYER_TYPE) int {
+	mine := len(g.GetAliveUnitiesByPlayerId(id))
+	enemy := len(g.GetAliveUnitiesByPlayerId(enemyOf(id)))
+
+	return (mine - enemy) * 100
+}
+
+// Coins earned, minus the ones left unspent
+func economyFitness(g *Game, id PLAYER_TYPE) int {
+	p := g.GetPlayerById(id)
+
+	return p.TotalCoins - p.Coins*2
+}
+
+// Winning early scores the most, a loser still gets up to half for holding
+// out longer
+func speedFitness(g *Game, id PLAYER_TYPE) int {
+	timeout := max(int(TIMEOUT_MINUTES), 1)
+	elapsed := min(g.DisplayTime, timeout)
+
+	if g.Draw {
+		return 500
+	}
+	if g.Winner == id {
+		return 1000 + 1000*(timeout-elapsed)/timeout
+	}
+	return 500 * elapsed / timeout
+}
+
+func enemyOf(id PLAYER_TYPE) PLAYER_TYPE {
+	if id == BLUE {
+		return RED
+	}
+	return BLUE
+}
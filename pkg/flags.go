@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+)
+
+// RegisterFlags binds the match options to fs, speed is the default number
+// of ticks per frame for this kind of match.
+func (c *MatchConfig) RegisterFlags(fs *flag.FlagSet, speed int) {
+	fs.Uint64Var(&c.Seed, "seed", 0, "game seed, a random one is picked when 0")
+	fs.IntVar(&c.Speed, "speed", speed, "ticks simulated per frame")
+	fs.StringVar(&c.Record, "record", "", "write the replay of the game to this file")
+	fs.StringVar(&c.Load, "load", "", "start from a saved game instead of a new one")
+	fs.StringVar(&c.Save, "save", "", "save the game to this file when pressing F5")
+	RegisterWarRulesFlag(fs, &c.WarRules)
+}
+
+// RegisterModelFlags binds the model file of each side to fs.
+func (c *MatchConfig) RegisterModelFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.BlueModel, "blue", BEST_MODEL_FILE, "model file playing blue")
+	fs.StringVar(&c.RedModel, "red", BEST_MODEL_FILE, "model file playing red")
+}
+
+func (c *TrainConfig) RegisterFlags(fs *flag.FlagSet) {
+	fs.Uint64Var(&c.Seed, "seed", 0, "training seed, a random one is picked when 0")
+	fs.IntVar(&c.Workers, "workers", 0, "matches played at the same time, 0 uses every CPU")
+	fs.IntVar(&c.Generations, "generations", GENERATIONS, "generations to train")
+	fs.IntVar(&c.GamesPerGen, "games", GAME_PER_GEN, "games played per generation, spread over the population")
+	fs.Float64Var(&c.MutationRate, "mutation-rate", MUTATION_RATE, "chance of mutating each weight")
+	fs.StringVar(&c.Mutation, "mutation", MUTATION_RESET, "mutation: reset draws a new weight, gaussian adds noise to it")
+	fs.Float64Var(&c.Sigma, "sigma", MUTATION_SIGMA, "start standard deviation of the gaussian mutation")
+	fs.StringVar(&c.Adapt, "adapt", ADAPT_NONE, "how sigma changes over the generations: none, decay or one-fifth")
+	fs.Func("layer-rates", "comma separated mutation rate of each layer from the input one, mutation-rate is used for the rest", func(s string) error {
+		c.LayerRates = nil
+		for _, v := range strings.Split(s, ",") {
+			rate, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return err
+			}
+			c.LayerRates = append(c.LayerRates, rate)
+		}
+		return nil
+	})
+	fs.Func("topology", "layers of new models as size:activation pairs from the first hidden one to the output one, activations are tanh, relu, sigmoid or linear (default "+FormatTopology(DEFAULT_TOPOLOGY)+")", func(s string) error {
+		t, err := ParseTopology(s)
+		c.Topology = t
+		return err
+	})
+	fs.Func("observation", "features new models read: default, full or a comma separated list of "+strings.Join(FeatureNames(), ", ")+" (default default)", func(s string) error {
+		o, err := ParseObservation(s)
+		c.Observation = o
+		return err
+	})
+	fs.Func("actions", "outputs new models write: default, full or a comma separated list of "+strings.Join(OutputNames(), ", ")+" (default default)", func(s string) error {
+		o, err := ParseOutputs(s)
+		c.Outputs = o
+		return err
+	})
+	RegisterWarRulesFlag(fs, &c.WarRules)
+	fs.IntVar(&c.Population, "population", POPULATION_SIZE, "models in every generation")
+	fs.IntVar(&c.Elite, "elite", ELITE_SIZE, "fittest models kept unchanged in the next generation")
+	fs.StringVar(&c.Selection, "selection", SELECTION_TOURNAMENT, "parent selection: tournament or roulette")
+	fs.IntVar(&c.TournamentSize, "tournament-size", TOURNAMENT_SIZE, "models drawn for each tournament selection")
+	fs.StringVar(&c.Crossover, "crossover", CROSSOVER_UNIFORM, "crossover: uniform or layer")
+	fs.Float64Var(&c.CrossoverRate, "crossover-rate", CROSSOVER_RATE, "chance of crossing two parents instead of copying them")
+	fs.StringVar(&c.OutputDir, "out", TRAIN_DIR, "directory the model of every generation is written to")
+	fs.StringVar(&c.BestFile, "best", BEST_MODEL_FILE, "file the best model is written to")
+	fs.BoolVar(&c.Record, "record", false, "write the replay of every game to the replays folder of the output directory")
+	fs.StringVar(&c.Fitness, "fitness", FITNESS_POINTS, "how models are scored: "+strings.Join(FitnessNames(), ", "))
+	fs.StringVar(&c.Ladder, "ladder", LADDER_FILE, "ladder file the model of every generation is placed on, empty to leave the ladder out")
+	fs.StringVar(&c.Metrics, "metrics", "", "write match and generation metrics to the output directory as csv or jsonl")
+	fs.BoolVar(&c.Resume, "resume", false, "go on from the checkpoint in the output directory, with the config it was started with")
+}
+
+func (c *EvalConfig) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.BlueModel, "blue", BEST_MODEL_FILE, "model file playing blue")
+	fs.StringVar(&c.RedModel, "red", BEST_MODEL_FILE, "model file playing red")
+	fs.IntVar(&c.Games, "games", EVAL_GAMES, "games to play")
+	fs.Uint64Var(&c.Seed, "seed", 0, "seed of the game seeds, a random one is picked when 0")
+	fs.IntVar(&c.Workers, "workers", 0, "matches played at the same time, 0 uses every CPU")
+	fs.StringVar(&c.Ladder, "ladder", LADDER_FILE, "ladder file updated with every game, empty to leave the ladder out")
+	RegisterWarRulesFlag(fs, &c.WarRules)
+}
+
+func (c *TournamentConfig) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.Seeds, "seeds", TOURNAMENT_SEEDS, "game seeds per pairing, each one played with both colours")
+	fs.Uint64Var(&c.Seed, "seed", 0, "seed of the game seeds, a random one is picked when 0")
+	fs.IntVar(&c.Workers, "workers", 0, "matches played at the same time, 0 uses every CPU")
+	fs.StringVar(&c.Ladder, "ladder", LADDER_FILE, "ladder file updated with every game, empty to leave the ladder out")
+	RegisterWarRulesFlag(fs, &c.WarRules)
+}
+
+// RegisterWarRulesFlag binds the rules of the war phase to fs.
+func RegisterWarRulesFlag(fs *flag.FlagSet, r *WarRules) {
+	fs.Func("war-rules", "actions allowed once the border is down: "+strings.Join(WarRulesPresets(), ", ")+" or a comma separated list of reinforcements[:cost percent], upgrades, income, stances, retreat (default "+WAR_RULES_NONE+")", func(s string) error {
+		rules, err := ParseWarRules(s)
+		*r = rules
+		return err
+	})
+}
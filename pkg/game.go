@@ -7,8 +7,6 @@ import (
 	"math/rand/v2"
 	"time"
 
-	"github.com/asaskevich/EventBus"
-	rl "github.com/gen2brain/raylib-go/raylib"
 	"github.com/google/uuid"
 )
 
@@ -28,6 +26,12 @@ var TIMEOUT_MINUTES = 6 * time.Minute.Seconds() * scaleDown(GAME_SPEED)
 
 const BASE_THICKNESS = 20
 
+// The simulation runs on a fixed clock. Each tick moves units and resolves
+// combat once, TICK_RATE ticks make one simulated second (attack cooldowns)
+// and DisplayTime, the economy clock, moves every TICKS_PER_DISPLAY_SECOND.
+const TICK_RATE = 60
+const TICKS_PER_DISPLAY_SECOND = 2
+
 type PLAYER_TYPE string
 type TOWER_TYPE int
 type UNITY_TYPE int
@@ -35,12 +39,19 @@ type UNITY_STATE int
 type UNITY_SURROUND int
 type ACTION string
 
-var Bus = EventBus.New()
 var UNITY_THICK = map[UNITY_TYPE]int{
 	SOLDIER: 5,
+	BOMBER:  7,
 }
 var UNITY_BASE_COST = map[UNITY_TYPE]int{
 	SOLDIER: 10,
+	BOMBER:  25,
+}
+
+// Hp of a unit for each technology level
+var UNITY_BASE_HP = map[UNITY_TYPE]int{
+	SOLDIER: 10,
+	BOMBER:  6,
 }
 var TECH_UPDATE_COST = map[int]int{
 	1: 100,
@@ -95,12 +106,14 @@ const (
 	UPDATE_MINING ACTION = "UPDATE_MINING"
 	BUY_SOLDIER   ACTION = "BUY_SOLDIER"
 	DO_NOTHING    ACTION = "DO_NOTHING"
+	DROP_BORDER   ACTION = "DROP_BORDER"
+	BUY_BOMBER    ACTION = "BUY_BOMBER"
 )
 
 type GameField struct {
 	Width           int
 	Height          int
-	Border          rl.Rectangle
+	Border          CollisionBox
 	BorderThickness int
 	BorderIsUp      bool
 }
@@ -114,7 +127,7 @@ func CreateField(size int) GameField {
 	return GameField{
 		Width:           size,
 		Height:          size,
-		Border:          rl.NewRectangle(0, 0, float32(size), float32(size)),
+		Border:          NewCollisionBox(0, 0, float32(size), float32(size)),
 		BorderThickness: 3,
 		BorderIsUp:      true,
 	}
@@ -126,6 +139,8 @@ type Player struct {
 	TotalCoins      int
 	TechnologyLevel int
 	MiningLevel     int
+	// How the army picks its targets, empty is STANCE_ADVANCE
+	Stance STANCE
 }
 
 type Screen struct {
@@ -133,7 +148,23 @@ type Screen struct {
 	Height int
 }
 
-type CollisionBox = rl.Rectangle
+type CollisionBox struct {
+	X      float32
+	Y      float32
+	Width  float32
+	Height float32
+}
+
+func NewCollisionBox(x, y, width, height float32) CollisionBox {
+	return CollisionBox{X: x, Y: y, Width: width, Height: height}
+}
+
+// Collides reports whether both boxes overlap, same rule as raylib's
+// CheckCollisionRecs so the simulation does not depend on it.
+func (b CollisionBox) Collides(o CollisionBox) bool {
+	return b.X < o.X+o.Width && b.X+b.Width > o.X &&
+		b.Y < o.Y+o.Height && b.Y+b.Height > o.Y
+}
 
 func CreatePlayer(id PLAYER_TYPE) *Player {
 	return &Player{Id: id, Coins: 100, MiningLevel: 1, TechnologyLevel: 1}
@@ -143,7 +174,7 @@ type Game struct {
 	ID          uuid.UUID
 	Field       GameField
 	DisplayTime int
-	ElapsedTime float64
+	Tick        int
 	Screen      Screen
 	PlayerRed   Player
 	PlayerBlue  Player
@@ -152,16 +183,33 @@ type Game struct {
 	Towers      []Tower
 	Wall        Position
 	Winner      PLAYER_TYPE
+	Draw        bool
 	Finished    bool
+	Seed        uint64
+	// What players can still do once the border is down
+	WarRules  WarRules
+	rng       *rand.Rand
+	rngSource *rand.PCG
+
+	events        *eventBus
+	subscriptions []*Subscription
+
+	recording *Replay
 }
 
 type CreateGameArgs struct {
+	// Ticks simulated on every Step
 	Speed int
+	// Seed for unit placement, a random one is picked when zero
+	Seed uint64
+	// Actions allowed once the border is down, none when empty
+	WarRules WarRules
 }
 
 type GameUpdateEvent struct {
 	GameID           uuid.UUID
 	Owner            PLAYER_TYPE
+	Tick             int
 	Time             int
 	Coins            int
 	TotalCoins       int
@@ -172,9 +220,30 @@ type GameUpdateEvent struct {
 	Unities          int
 	EnemyUnities     int
 	UnityCost        int
+	// Units still alive, Unities counts every one bought
+	AliveUnities      int
+	EnemyAliveUnities int
+	EnemyTechLevel    int
+	EnemyMiningLevel  int
+	// Display seconds until the border drops, 0 once it is down
+	BorderDropIn int
+	// Alive units only, see armySummary
+	AvgHp        float64
+	EnemyAvgHp   float64
+	Advance      float64
+	EnemyAdvance float64
+	Front        float64
+	EnemyFront   float64
+	Stance       STANCE
 }
 
 func NewGame(args CreateGameArgs) Game {
+	seed := args.Seed
+	if seed == 0 {
+		seed = NewSeed()
+	}
+	src := rand.NewPCG(seed, seed)
+
 	return Game{
 		ID:         uuid.New(),
 		Field:      CreateField(400),
@@ -183,9 +252,25 @@ func NewGame(args CreateGameArgs) Game {
 		PlayerRed:  *CreatePlayer(RED),
 		PlayerBlue: *CreatePlayer(BLUE),
 		Finished:   false,
+		Seed:       seed,
+		WarRules:   args.WarRules,
+		rng:        rand.New(src),
+		rngSource:  src,
+		events:     newEventBus(),
 	}
 }
 
+// NewSeed picks a random seed, log it to be able to replay the run.
+func NewSeed() uint64 {
+	return rand.Uint64()
+}
+
+// NewRand returns a random source that always yields the same sequence for
+// the same seed.
+func NewRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
 type Tower struct {
 	Id           int
 	Hp           int
@@ -219,72 +304,6 @@ type ActionEvent struct {
 	Action ACTION
 }
 
-func RunGame(g *Game) PLAYER_TYPE {
-	rl.SetTraceLogLevel(rl.LogError)
-	rl.InitWindow(int32(g.Screen.Width), int32(g.Screen.Height), fmt.Sprint("War", g.ID))
-
-	camera := rl.NewCamera2D(
-		rl.NewVector2(0, 0),
-		rl.NewVector2(0, 0),
-		0.0,
-		1.0,
-	)
-
-	for g.Winner == "" {
-		if rl.WindowShouldClose() {
-			break
-		}
-
-		rl.SetTargetFPS(60)
-		rl.BeginDrawing()
-		rl.ClearBackground(rl.Black)
-		rl.BeginMode2D(camera)
-
-		g.Update()
-
-		if g.Field.BorderIsUp {
-			g.ListenKeyPress()
-		}
-
-		if !g.Field.BorderIsUp {
-			g.RunWar(rl.GetTime())
-		}
-
-		g.Render()
-		g.RenderUI()
-
-		rl.EndDrawing()
-	}
-
-	return g.Winner
-}
-
-func (u Unity) GetColor() rl.Color {
-	if u.State == DEAD {
-		return rl.Gray
-	}
-
-	switch u.PlayerOwner {
-	case RED:
-		return rl.Red
-	case BLUE:
-		return rl.Blue
-	}
-
-	return rl.White
-}
-
-func (u Tower) GetColor() rl.Color {
-	switch u.PlayerOwner {
-	case RED:
-		return rl.Red
-	case BLUE:
-		return rl.Blue
-	}
-
-	return rl.White
-}
-
 func (g *Game) Init() {
 	blue := g.PlayerBlue
 	red := g.PlayerRed
@@ -301,54 +320,51 @@ func (g *Game) Init() {
 	g.PlayerRed = red
 }
 
-func (g *Game) ListenKeyPress() {
-	// BLUE
-	if rl.IsKeyPressed(rl.KeyOne) {
-		g.BuyUnity(SOLDIER, BLUE)
-		fmt.Println("Blue bought a soldier")
-	}
-	if rl.IsKeyPressed(rl.KeyTwo) {
-		g.InvestMining(BLUE)
-		fmt.Println("Blue invested in mining")
-	}
-	if rl.IsKeyPressed(rl.KeyThree) {
-		g.InvestTechnology(BLUE)
-		fmt.Println("Blue invested in tech")
-	}
+// Step advances the simulation by Speed ticks, stopping early once the game
+// has a winner.
+func (g *Game) Step() {
+	for range max(g.Speed, 1) {
+		if g.Winner != "" {
+			return
+		}
 
-	// RED
-	if rl.IsKeyPressed(rl.KeyQ) {
-		g.BuyUnity(SOLDIER, RED)
-		fmt.Println("Red bought a soldier")
-	}
-	if rl.IsKeyPressed(rl.KeyW) {
-		g.InvestMining(RED)
-		fmt.Println("Red invested in mining")
-	}
-	if rl.IsKeyPressed(rl.KeyE) {
-		g.InvestTechnology(RED)
-		fmt.Println("Red invested in tech")
-	}
+		g.Tick++
+		g.Update()
+
+		if !g.Field.BorderIsUp {
+			g.RunWar()
+		}
 
-	// BORDER
-	if rl.IsKeyPressed(rl.KeySpace) {
-		g.Field.BorderIsUp = false
+		if g.recording != nil {
+			g.recording.checkpoint(g)
+		}
 	}
 }
 
-func (g *Game) Update() {
-	t := rl.GetTime()
-	diff := t - float64(g.ElapsedTime)
+// Now returns the simulated seconds since the game started.
+func (g Game) Now() float64 {
+	return float64(g.Tick) / TICK_RATE
+}
 
+func (g *Game) Update() {
 	if !g.Field.BorderIsUp {
 		uB := len(g.GetAliveUnitiesByPlayerId(BLUE))
 		uR := len(g.GetAliveUnitiesByPlayerId(RED))
 
 		if g.DisplayTime > int(TIMEOUT_MINUTES) {
+			g.publish(TIMEOUT_TOPIC, TimeoutEvent{
+				GameID:      g.ID,
+				Tick:        g.Tick,
+				BlueUnities: uB,
+				RedUnities:  uR,
+			})
+
+			// Ties still go to blue, Draw tells them apart
+			g.Draw = uB == uR
 			if uB >= uR {
-				g.Winner = BLUE
+				g.setWinner(BLUE)
 			} else {
-				g.Winner = RED
+				g.setWinner(RED)
 			}
 
 			fmt.Println("GAME TIMEOUT")
@@ -356,34 +372,34 @@ func (g *Game) Update() {
 		}
 
 		if uR == 0 && uB > 0 {
-			g.Winner = BLUE
+			g.setWinner(BLUE)
 			fmt.Println("BLUE WINS")
 			return
 		}
 
 		if uB == 0 && uR > 0 {
-			g.Winner = RED
+			g.setWinner(RED)
 			fmt.Println("RED WINS")
 			return
 		}
 
-		if uR == 0 && uR == 0 {
-			g.Winner = BLUE
+		if uR == 0 && uB == 0 {
+			g.Draw = true
+			g.setWinner(BLUE)
 			fmt.Println("BLUE WINS")
 			return
 		}
 	}
 
 	if g.Field.BorderIsUp && g.DisplayTime > int(FIVE_MINUTES.Seconds()) {
-		g.Field.BorderIsUp = false
+		g.DropBorder()
 		return
 	}
 
-	if diff > (1.0 / GAME_SPEED) {
-		g.ElapsedTime = t
+	if g.Tick%TICKS_PER_DISPLAY_SECOND == 0 {
 		g.DisplayTime += 1
 
-		if g.Field.BorderIsUp {
+		if g.Field.BorderIsUp || g.WarRules.Income {
 			cB := g.PlayerBlue.CalculateCoinsToReceive()
 			cR := g.PlayerRed.CalculateCoinsToReceive()
 
@@ -394,113 +410,77 @@ func (g *Game) Update() {
 			g.PlayerRed.TotalCoins += cR
 		}
 
-		if Bus != nil {
-			Bus.Publish(fmt.Sprint("game:", g.ID, "/update"), g.NewGameUpdateEvent(BLUE))
-			Bus.Publish(fmt.Sprint("game:", g.ID, "/update"), g.NewGameUpdateEvent(RED))
-		}
+		g.publish(UPDATE_TOPIC, g.NewGameUpdateEvent(BLUE))
+		g.publish(UPDATE_TOPIC, g.NewGameUpdateEvent(RED))
 	}
 
 }
 
-func (g Game) NewGameUpdateEvent(p PLAYER_TYPE) GameUpdateEvent {
-	if p == BLUE {
-		return GameUpdateEvent{
-			GameID:           g.ID,
-			Owner:            p,
-			Time:             g.DisplayTime,
-			TechLevel:        g.PlayerBlue.TechnologyLevel,
-			TechUpdateCost:   TECH_UPDATE_COST[g.PlayerBlue.TechnologyLevel],
-			MiningLevel:      g.PlayerBlue.MiningLevel,
-			MiningUpdateCost: TECH_UPDATE_COST[g.PlayerBlue.MiningLevel],
-			Coins:            g.PlayerBlue.Coins,
-			UnityCost:        g.GetCurrentUnityCost(SOLDIER),
-			Unities:          len(g.GetUnitiesByPlayerId(BLUE)),
-			EnemyUnities:     len(g.GetUnitiesByPlayerId(RED)),
-			TotalCoins:       g.PlayerBlue.TotalCoins,
-		}
-	}
-
-	return GameUpdateEvent{
-		GameID:           g.ID,
-		Owner:            p,
-		Time:             g.DisplayTime,
-		TechLevel:        g.PlayerRed.TechnologyLevel,
-		TechUpdateCost:   TECH_UPDATE_COST[g.PlayerRed.TechnologyLevel],
-		MiningLevel:      g.PlayerRed.MiningLevel,
-		MiningUpdateCost: TECH_UPDATE_COST[g.PlayerRed.MiningLevel],
-		Coins:            g.PlayerRed.Coins,
-		UnityCost:        g.GetCurrentUnityCost(SOLDIER),
-		Unities:          len(g.GetUnitiesByPlayerId(RED)),
-		EnemyUnities:     len(g.GetUnitiesByPlayerId(BLUE)),
-		TotalCoins:       g.PlayerRed.TotalCoins,
-	}
+func (g *Game) setWinner(p PLAYER_TYPE) {
+	g.Winner = p
+	g.publish(GAME_OVER_TOPIC, GameOverEvent{
+		GameID: g.ID,
+		Tick:   g.Tick,
+		Winner: p,
+		Draw:   g.Draw,
+		Seed:   g.Seed,
+	})
 }
 
-func (g *Game) Render() {
-	// Field
-	rec := rl.NewRectangle(0, 0, float32(g.Field.Width), float32(g.Field.Height))
-	rl.DrawRectangleLinesEx(rec, 3, rl.White)
-
-	// Border
-	if g.Field.BorderIsUp {
-		rl.DrawRectangle(0, int32(g.Screen.Height/2), int32(g.Screen.Width), 5, rl.Orange)
-	}
-
-	// Towers
-	for _, t := range g.Towers {
-		switch t.Type {
-		case BASE:
-			rl.DrawRectangle(int32(t.Position.X), int32(t.Position.Y), int32(t.Thickness), int32(t.Thickness), t.GetColor())
-		}
+// DropBorder starts the war phase.
+func (g *Game) DropBorder() {
+	if !g.Field.BorderIsUp {
+		return
 	}
 
-	// Unities
-	for _, u := range g.Unities {
-		t := UNITY_THICK[u.Type]
-
-		switch u.Type {
-		case SOLDIER:
-			if u.State == DEAD {
-				continue
-			}
-			x := float64(u.Position.X) - float64(t)/2
-			y := float64(u.Position.Y) - float64(t)/2
-			rec := rl.NewRectangle(float32(x), float32(y), float32(t), float32(t))
-
-			rl.DrawRectangleRec(rec, u.GetColor())
-		case BOMBER:
-			rl.DrawCircle(int32(u.Position.X), int32(u.Position.Y), float32(t), u.GetColor())
-		}
-	}
+	g.Field.BorderIsUp = false
+	g.publish(BORDER_DROPPED_TOPIC, BorderDroppedEvent{
+		GameID:      g.ID,
+		Tick:        g.Tick,
+		DisplayTime: g.DisplayTime,
+	})
 }
 
-func (g *Game) RenderUI() {
-	rl.DrawText(fmt.Sprint("Time:", g.DisplayTime), 0, 0, 16, rl.White)
-	rl.DrawText(fmt.Sprint("Coins Blue:", g.PlayerBlue.Coins), 0, 25, 16, rl.White)
-	rl.DrawText(fmt.Sprint("Coins Red:", g.PlayerRed.Coins), 0, 45, 16, rl.White)
+func (g Game) NewGameUpdateEvent(p PLAYER_TYPE) GameUpdateEvent {
+	player := g.GetPlayerById(p)
+	enemy := g.GetPlayerById(enemyOf(p))
+
+	e := GameUpdateEvent{
+		GameID:            g.ID,
+		Owner:             p,
+		Tick:              g.Tick,
+		Time:              g.DisplayTime,
+		TechLevel:         player.TechnologyLevel,
+		TechUpdateCost:    TECH_UPDATE_COST[player.TechnologyLevel],
+		MiningLevel:       player.MiningLevel,
+		MiningUpdateCost:  TECH_UPDATE_COST[player.MiningLevel],
+		Coins:             player.Coins,
+		UnityCost:         g.GetCurrentUnityCost(SOLDIER),
+		Unities:           len(g.GetUnitiesByPlayerId(p)),
+		EnemyUnities:      len(g.GetUnitiesByPlayerId(enemy.Id)),
+		TotalCoins:        player.TotalCoins,
+		AliveUnities:      len(g.GetAliveUnitiesByPlayerId(p)),
+		EnemyAliveUnities: len(g.GetAliveUnitiesByPlayerId(enemy.Id)),
+		EnemyTechLevel:    enemy.TechnologyLevel,
+		EnemyMiningLevel:  enemy.MiningLevel,
+		BorderDropIn:      g.borderDropIn(),
+		Stance:            player.GetStance(),
+	}
+	e.AvgHp, e.Advance, e.Front = g.armySummary(p)
+	e.EnemyAvgHp, e.EnemyAdvance, e.EnemyFront = g.armySummary(enemy.Id)
 
-	rl.DrawText(fmt.Sprint("Mining Level Blue:", g.PlayerBlue.MiningLevel), 0, 65, 16, rl.White)
-	rl.DrawText(fmt.Sprint("Tech Level Blue:", g.PlayerBlue.TechnologyLevel), 0, 85, 16, rl.White)
+	return e
+}
 
-	rl.DrawText(fmt.Sprint("Mining Level Red:", g.PlayerRed.MiningLevel), 0, 105, 16, rl.White)
-	rl.DrawText(fmt.Sprint("Tech Level Red:", g.PlayerRed.TechnologyLevel), 0, 125, 16, rl.White)
+func (g *Game) RunWar() {
+	now := g.Now()
 
-	var aliveUnities = 0
-	var deadUnitites = 0
-	for _, u := range g.Unities {
-		if u.State != DEAD {
-			aliveUnities += 1
-		}
-		if u.State == DEAD {
-			deadUnitites++
-		}
+	positions := make([]Position, len(g.Unities))
+	for i, u := range g.Unities {
+		positions[i] = u.Position
 	}
+	defer g.publishMoves(positions)
 
-	rl.DrawText(fmt.Sprint("Unities:", aliveUnities), 0, 145, 16, rl.White)
-	rl.DrawText(fmt.Sprint("Dead Unities:", deadUnitites), 0, 160, 16, rl.White)
-}
-
-func (g *Game) RunWar(time float64) {
 Outer:
 	for i, current := range g.Unities {
 		if current.Hp <= 0 {
@@ -511,6 +491,11 @@ Outer:
 			continue
 		}
 
+		if g.GetPlayerById(current.PlayerOwner).GetStance() == STANCE_RETREAT {
+			g.retreat(i, now)
+			continue
+		}
+
 		switch current.State {
 		case IDDLE:
 			u, p, err := g.CalculateUnityNextPosition(current)
@@ -533,10 +518,17 @@ Outer:
 					continue Outer
 				}
 
-				if rl.CheckCollisionRecs(current.GetCollisionBox(), u.GetCollisionBox()) {
+				if current.GetCollisionBox().Collides(u.GetCollisionBox()) {
 					if u.Id == current.TargetUnityId && u.PlayerOwner != current.PlayerOwner {
 						g.Unities[i].State = COMBAT
 						g.Unities[i].TargetUnityId = u.Id
+						g.publish(COMBAT_STARTED_TOPIC, CombatStartedEvent{
+							GameID:     g.ID,
+							Tick:       g.Tick,
+							AttackerId: current.Id,
+							TargetId:   u.Id,
+							Owner:      current.PlayerOwner,
+						})
 						continue Outer
 					}
 
@@ -602,16 +594,23 @@ Outer:
 			target, err := g.FindTargetUnityById(current.TargetUnityId)
 			if err != nil || target.State == DEAD {
 				g.Unities[i].State = IDDLE
+				continue
+			}
+
+			// The target walked away, go after it again
+			if !current.GetCollisionBox().Collides(target.GetCollisionBox()) {
+				g.Unities[i].State = IDDLE
+				continue
 			}
 
-			attkCd := current.getCoolDown()
+			attkCd := current.getCoolDown(now)
 			if attkCd != 0 {
 				continue
 			}
 
 			dmg := g.CalculateUnityDamage(current, target)
-			g.ExecuteDamage(target.Id, dmg)
-			g.Unities[i].LastAttackAt = rl.GetTime()
+			g.ExecuteDamage(current.Id, target.Id, dmg)
+			g.Unities[i].LastAttackAt = now
 		}
 	}
 }
@@ -624,14 +623,14 @@ func getNewUnityPositionByPlayer(id PLAYER_TYPE, game Game, uType UNITY_TYPE) Po
 	switch id {
 	case BLUE:
 		pos := Position{
-			X: bP.X - rand.IntN(100) + rand.IntN(100),
-			Y: bP.Y + 10 + rand.IntN(100) + BASE_THICKNESS,
+			X: bP.X - game.rng.IntN(100) + game.rng.IntN(100),
+			Y: bP.Y + 10 + game.rng.IntN(100) + BASE_THICKNESS,
 		}
 
-		rec := rl.NewRectangle(float32(pos.X)-float32(t/2), float32(pos.Y)-float32(t/2), float32(t), float32(t))
+		rec := NewCollisionBox(float32(pos.X)-float32(t/2), float32(pos.Y)-float32(t/2), float32(t), float32(t))
 
 		for _, u := range unities {
-			if rl.CheckCollisionRecs(rec, u.GetCollisionBox()) {
+			if rec.Collides(u.GetCollisionBox()) {
 				return getNewUnityPositionByPlayer(id, game, uType)
 			}
 		}
@@ -640,14 +639,14 @@ func getNewUnityPositionByPlayer(id PLAYER_TYPE, game Game, uType UNITY_TYPE) Po
 
 	case RED:
 		pos := Position{
-			X: bP.X - rand.IntN(100) + rand.IntN(100),
-			Y: bP.Y - 10 - rand.IntN(100) - BASE_THICKNESS,
+			X: bP.X - game.rng.IntN(100) + game.rng.IntN(100),
+			Y: bP.Y - 10 - game.rng.IntN(100) - BASE_THICKNESS,
 		}
 
-		rec := rl.NewRectangle(float32(pos.X)-float32(t/2), float32(pos.Y)-float32(t/2), float32(t), float32(t))
+		rec := NewCollisionBox(float32(pos.X)-float32(t/2), float32(pos.Y)-float32(t/2), float32(t), float32(t))
 
 		for _, u := range unities {
-			if rl.CheckCollisionRecs(rec, u.GetCollisionBox()) {
+			if rec.Collides(u.GetCollisionBox()) {
 				return getNewUnityPositionByPlayer(id, game, uType)
 			}
 		}
@@ -672,16 +671,16 @@ func (g *Game) BuyUnity(u UNITY_TYPE, id PLAYER_TYPE) error {
 	p := g.GetPlayer(id)
 
 	switch u {
-	case SOLDIER:
+	case SOLDIER, BOMBER:
 		if p.Coins < g.GetCurrentUnityCost(u) {
 			return errors.New("Not enough coins")
 		}
 
 		p.Coins -= g.GetCurrentUnityCost(u)
-		g.addUnity(SOLDIER, id)
+		return g.addUnity(u, id, LANE_ANY)
 	}
 
-	return nil
+	return errors.New("Invalid Unity Type")
 }
 
 func (g *Game) GetPlayer(id PLAYER_TYPE) *Player {
@@ -705,7 +704,9 @@ func (p Player) CalculateCoinsToReceive() int {
 	return 1
 }
 
-func (g *Game) addUnity(t UNITY_TYPE, player PLAYER_TYPE) error {
+// addUnity spawns a unit in the given lane, LANE_ANY spawns it anywhere in
+// front of the base.
+func (g *Game) addUnity(t UNITY_TYPE, player PLAYER_TYPE, lane int) error {
 	id := 1
 
 	if len(g.Unities) != 0 {
@@ -713,35 +714,59 @@ func (g *Game) addUnity(t UNITY_TYPE, player PLAYER_TYPE) error {
 		id = lastUnity.Id + 1
 	}
 
+	var u Unity
 	switch t {
 	case SOLDIER:
-		pos := getNewUnityPositionByPlayer(player, *g, t)
-		u := Unity{
-			Id:                    id,
-			Hp:                    10,
-			AcumulatedDamage:      0,
+		u = Unity{
+			Hp:                    UNITY_BASE_HP[SOLDIER],
 			Power:                 7,
 			Defense:               2,
-			Type:                  SOLDIER,
-			Position:              pos,
-			PlayerOwner:           player,
 			Speed:                 1,
-			State:                 IDDLE,
 			AttackCooldownSeconds: 0.5,
 		}
+	case BOMBER:
+		u = Unity{
+			Hp:                    UNITY_BASE_HP[BOMBER],
+			Power:                 14,
+			Defense:               1,
+			Speed:                 1,
+			AttackCooldownSeconds: 1,
+		}
+	default:
+		return errors.New("Invalid Unity Type")
+	}
 
-		g.Unities = append(g.Unities, u)
-		return nil
+	pos := Position{}
+	if lane == LANE_ANY {
+		pos = getNewUnityPositionByPlayer(player, *g, t)
+	} else {
+		p, err := getNewUnityPositionInLane(player, *g, t, lane)
+		if err != nil {
+			return err
+		}
+		pos = p
 	}
 
-	return errors.New("Invalid Unity Type")
+	u.Id = id
+	u.Type = t
+	u.Position = pos
+	u.PlayerOwner = player
+	u.State = IDDLE
+
+	g.Unities = append(g.Unities, u)
+	g.publish(UNITY_SPAWNED_TOPIC, UnitySpawnedEvent{
+		GameID: g.ID,
+		Tick:   g.Tick,
+		Unity:  u,
+	})
+	return nil
 }
 
 func (g *Game) InvestMining(id PLAYER_TYPE) error {
 	p := g.GetPlayer(id)
 
 	if p.MiningLevel >= 3 {
-		return nil
+		return errors.New("Already at max level")
 	}
 
 	switch p.MiningLevel {
@@ -752,6 +777,7 @@ func (g *Game) InvestMining(id PLAYER_TYPE) error {
 
 		p.Coins -= MINING_LEVEL_COST[1]
 		p.MiningLevel++
+		g.publishUpgrade(id, UPDATE_MINING, p.MiningLevel, MINING_LEVEL_COST[1])
 	case 2:
 		if p.Coins < MINING_LEVEL_COST[2] {
 			return errors.New("Not enough coins")
@@ -759,6 +785,7 @@ func (g *Game) InvestMining(id PLAYER_TYPE) error {
 
 		p.Coins -= MINING_LEVEL_COST[2]
 		p.MiningLevel++
+		g.publishUpgrade(id, UPDATE_MINING, p.MiningLevel, MINING_LEVEL_COST[2])
 	}
 
 	return nil
@@ -768,7 +795,7 @@ func (g *Game) InvestTechnology(id PLAYER_TYPE) error {
 	p := g.GetPlayer(id)
 
 	if p.TechnologyLevel >= 3 {
-		return nil
+		return errors.New("Already at max level")
 	}
 
 	switch p.TechnologyLevel {
@@ -778,6 +805,7 @@ func (g *Game) InvestTechnology(id PLAYER_TYPE) error {
 		}
 		p.Coins -= TECH_UPDATE_COST[1]
 		p.TechnologyLevel++
+		g.publishUpgrade(id, UPDATE_TECH, p.TechnologyLevel, TECH_UPDATE_COST[1])
 		g.ScaleUnitiesTech(id)
 	case 2:
 		if p.Coins < TECH_UPDATE_COST[2] {
@@ -786,6 +814,7 @@ func (g *Game) InvestTechnology(id PLAYER_TYPE) error {
 
 		p.Coins -= TECH_UPDATE_COST[2]
 		p.TechnologyLevel++
+		g.publishUpgrade(id, UPDATE_TECH, p.TechnologyLevel, TECH_UPDATE_COST[2])
 		g.ScaleUnitiesTech(id)
 	}
 
@@ -795,8 +824,8 @@ func (g *Game) InvestTechnology(id PLAYER_TYPE) error {
 func (g *Game) ScaleUnitiesTech(id PLAYER_TYPE) {
 	p := g.GetPlayer(id)
 	for i, u := range g.Unities {
-		if u.PlayerOwner == id {
-			g.Unities[i].Hp = 10 * p.TechnologyLevel
+		if u.PlayerOwner == id && u.State != DEAD {
+			g.Unities[i].Hp = UNITY_BASE_HP[u.Type] * p.TechnologyLevel
 		}
 	}
 }
@@ -841,10 +870,12 @@ func CalculateUnityCBox(p Position, u UNITY_TYPE) CollisionBox {
 
 	switch u {
 	case SOLDIER:
-		return rl.NewRectangle(float32(p.X)-float32(t)/2, float32(p.Y)-float32(t)/2, 5, 5)
+		return NewCollisionBox(float32(p.X)-float32(t)/2, float32(p.Y)-float32(t)/2, 5, 5)
+	case BOMBER:
+		return NewCollisionBox(float32(p.X)-float32(t)/2, float32(p.Y)-float32(t)/2, float32(t), float32(t))
 	}
 
-	return rl.NewRectangle(0, 0, 0, 0)
+	return NewCollisionBox(0, 0, 0, 0)
 }
 
 func (g Game) FindTargetUnityById(id int) (Unity, error) {
@@ -870,10 +901,7 @@ func FindClosestEnemyUnity(u Unity, g *Game) Unity {
 			continue
 		}
 
-		d := rl.Vector2Distance(
-			rl.NewVector2(float32(u.Position.X), float32(u.Position.Y)),
-			rl.NewVector2(float32(unity.Position.X), float32(unity.Position.Y)),
-		)
+		d := positionDistance(u.Position, unity.Position)
 
 		if d < float32(distance) || distance == 0 {
 			distance = float64(d)
@@ -896,19 +924,42 @@ func (g *Game) CalculateUnityDamage(attacker Unity, target Unity) int {
 	return dmg
 }
 
-func (g *Game) ExecuteDamage(unityId int, dmg int) {
-	g.Unities[unityId-1].AcumulatedDamage += dmg
-	techBoost := TECH_BOOST[g.GetPlayerById(g.Unities[unityId-1].PlayerOwner).TechnologyLevel]
-	hp := int(math.Floor(float64(g.Unities[unityId-1].Hp)*techBoost)) - g.Unities[unityId-1].AcumulatedDamage
+// RemainingHp is what is left of the unit's tech boosted hp after the damage
+// it took.
+func (g *Game) RemainingHp(u Unity) int {
+	techBoost := TECH_BOOST[g.GetPlayerById(u.PlayerOwner).TechnologyLevel]
+
+	return int(math.Floor(float64(u.Hp)*techBoost)) - u.AcumulatedDamage
+}
 
-	if hp <= 0 {
+func (g *Game) ExecuteDamage(attackerId int, unityId int, dmg int) {
+	g.Unities[unityId-1].AcumulatedDamage += dmg
+	hp := g.RemainingHp(g.Unities[unityId-1])
+
+	g.publish(DAMAGE_TOPIC, DamageEvent{
+		GameID:     g.ID,
+		Tick:       g.Tick,
+		AttackerId: attackerId,
+		TargetId:   unityId,
+		Damage:     dmg,
+		Hp:         max(hp, 0),
+	})
+
+	if hp <= 0 && g.Unities[unityId-1].State != DEAD {
 		g.Unities[unityId-1].State = DEAD
+		g.publish(UNITY_DIED_TOPIC, UnityDiedEvent{
+			GameID:   g.ID,
+			Tick:     g.Tick,
+			UnityId:  unityId,
+			Owner:    g.Unities[unityId-1].PlayerOwner,
+			KillerId: attackerId,
+		})
 	}
 }
 
 func (u Unity) GetCollisionBox() CollisionBox {
 	t := UNITY_THICK[u.Type]
-	return rl.NewRectangle(float32(u.Position.X)-float32(t)/2, float32(u.Position.Y)-float32(t)/2, 5, 5)
+	return NewCollisionBox(float32(u.Position.X)-float32(t)/2, float32(u.Position.Y)-float32(t)/2, float32(t), float32(t))
 }
 
 func (g *Game) GetUnityByPosition(p Position) (Unity, error) {
@@ -956,11 +1007,13 @@ func (g *Game) GetAvailableSurroundPosition(u Unity) (Position, UNITY_SURROUND,
 		BACK:  back,
 	}
 
+	// Fixed order, ranging over the map would make the game non-deterministic
 POS:
-	for k, p := range pos {
+	for _, k := range []UNITY_SURROUND{FRONT, RIGHT, LEFT, BACK} {
+		p := pos[k]
 		if !g.isPositionOverBorder(Position{X: int(p.X), Y: int(p.Y)}) {
 			for _, u := range g.Unities {
-				if rl.CheckCollisionRecs(p, u.GetCollisionBox()) {
+				if p.Collides(u.GetCollisionBox()) {
 					continue POS
 				}
 			}
@@ -988,7 +1041,7 @@ func (g Game) isPositionOverBorder(p Position) bool {
 }
 
 func (g *Game) CalculateUnityNextPosition(u Unity) (Unity, Position, error) {
-	closestUnity := FindClosestEnemyUnity(u, g)
+	closestUnity := g.FindStanceTarget(u)
 
 	if closestUnity.Id == 0 {
 		return Unity{}, Position{}, errors.New("No enemy found")
@@ -997,18 +1050,25 @@ func (g *Game) CalculateUnityNextPosition(u Unity) (Unity, Position, error) {
 	return closestUnity, closestUnity.Position, nil
 }
 
+func positionDistance(a, b Position) float32 {
+	dx := float64(a.X - b.X)
+	dy := float64(a.Y - b.Y)
+
+	return float32(math.Sqrt(dx*dx + dy*dy))
+}
+
 func (g *Game) UpdateUnityPosition(idx int, p Position) {
 	if !g.isPositionOverBorder(p) {
 		g.Unities[idx].Position = p
 	}
 }
 
-func (u Unity) getCoolDown() float64 {
+func (u Unity) getCoolDown(now float64) float64 {
 	if u.LastAttackAt == 0 {
 		return 0
 	}
 
-	diff := rl.GetTime() - u.LastAttackAt
+	diff := now - u.LastAttackAt
 
 	if diff > float64(u.AttackCooldownSeconds) {
 		return 0
@@ -1021,26 +1081,68 @@ func (g Game) GetCurrentUnityCost(u UNITY_TYPE) int {
 	f := int(math.Floor(float64(g.DisplayTime)/float64(time.Minute.Seconds()))) + 1
 
 	switch u {
-	case SOLDIER:
+	case SOLDIER, BOMBER:
+		if !g.Field.BorderIsUp && g.WarRules.Reinforcements {
+			return g.WarRules.reinforcementCost(f * UNITY_BASE_COST[u])
+		}
 		return f * UNITY_BASE_COST[u]
 	}
 
 	return UNITY_BASE_COST[SOLDIER]
 }
 
-func (g *Game) HandleActionEvent(e ActionEvent) {
+// HandleActionEvent takes the action of a player, once the border is down
+// only the ones the war rules allow. Rejected actions are published on
+// ACTION_REJECTED_TOPIC and their error returned.
+func (g *Game) HandleActionEvent(e ActionEvent) error {
+	err := g.takeAction(e)
+	if err != nil {
+		g.publish(ACTION_REJECTED_TOPIC, ActionRejectedEvent{
+			GameID: g.ID,
+			Tick:   g.Tick,
+			Owner:  e.Owner,
+			Action: e.Action,
+			Error:  err,
+		})
+	}
+
+	return err
+}
+
+func (g *Game) takeAction(e ActionEvent) error {
 	if !g.Field.BorderIsUp {
-		return
+		if err := g.WarRules.Allow(e.Action); err != nil {
+			return err
+		}
 	}
 
+	var err error
 	switch e.Action {
 	case BUY_SOLDIER:
-		g.BuyUnity(SOLDIER, e.Owner)
+		err = g.BuyUnity(SOLDIER, e.Owner)
 	case UPDATE_TECH:
-		g.InvestTechnology(e.Owner)
+		err = g.InvestTechnology(e.Owner)
 	case UPDATE_MINING:
-		g.InvestMining(e.Owner)
+		err = g.InvestMining(e.Owner)
+	case DROP_BORDER:
+		g.DropBorder()
+	case BUY_BOMBER:
+		err = g.BuyUnity(BOMBER, e.Owner)
+	case DO_NOTHING:
+		return nil
+	default:
+		err = g.handleCommand(e)
+	}
+
+	if err == nil && g.recording != nil {
+		g.recording.Actions = append(g.recording.Actions, ReplayAction{
+			Tick:   g.Tick,
+			Owner:  e.Owner,
+			Action: e.Action,
+		})
 	}
+
+	return err
 }
 
 func (g *Game) GetPlayerById(id PLAYER_TYPE) Player {
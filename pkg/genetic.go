@@ -0,0 +1,279 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+)
+
+const POPULATION_SIZE = 10
+const ELITE_SIZE = 2
+const TOURNAMENT_SIZE = 3
+const CROSSOVER_RATE = 0.7
+
+// Selection strategies
+const SELECTION_TOURNAMENT = "tournament"
+const SELECTION_ROULETTE = "roulette"
+
+// Crossover strategies
+const CROSSOVER_UNIFORM = "uniform"
+const CROSSOVER_LAYER = "layer"
+
+// Individual is a model of the population and how it did in the current
+// generation.
+type Individual struct {
+	Model   Model
+	Fitness int
+	Games   int
+	// Points summed over every game, Fitness is its average
+	Points int
+	// Bred this generation, ParentFitness is the best of its parents
+	Child         bool
+	ParentFitness int
+}
+
+// Pairing is a match between two members of the population.
+type Pairing struct {
+	Blue int
+	Red  int
+}
+
+// NewPopulation creates size random models reading the given inputs and
+// writing the given outputs with the given topology.
+func NewPopulation(size int, inputs []string, outputs []string, topology []LayerSpec, r *rand.Rand) []Individual {
+	pop := make([]Individual, size)
+	for i := range pop {
+		pop[i].Model.InitTopology(inputs, outputs, topology, r)
+	}
+
+	return pop
+}
+
+// PairPopulation draws games matches between members of a population of
+// size. Everyone plays once before anyone plays again and the colours swap
+// between rounds.
+func PairPopulation(size int, games int, r *rand.Rand) []Pairing {
+	res := []Pairing{}
+	round := 0
+
+	for len(res) < games {
+		perm := r.Perm(size)
+		for i := 0; i+1 < len(perm) && len(res) < games; i += 2 {
+			p := Pairing{Blue: perm[i], Red: perm[i+1]}
+			if round%2 == 1 {
+				p.Blue, p.Red = p.Red, p.Blue
+			}
+			res = append(res, p)
+		}
+		round++
+	}
+
+	return res
+}
+
+// Score sets the fitness of every individual to its average points.
+func Score(pop []Individual) {
+	for i := range pop {
+		pop[i].Fitness = 0
+		if pop[i].Games > 0 {
+			pop[i].Fitness = pop[i].Points / pop[i].Games
+		}
+		pop[i].Model.Points = pop[i].Fitness
+	}
+}
+
+// Played returns the indexes of the individuals that played a game this
+// generation, only they have a fitness. It is everyone when nobody played.
+func Played(pop []Individual) []int {
+	res := []int{}
+	for i, ind := range pop {
+		if ind.Games > 0 {
+			res = append(res, i)
+		}
+	}
+
+	if len(res) == 0 {
+		for i := range pop {
+			res = append(res, i)
+		}
+	}
+
+	return res
+}
+
+// Ranked returns the indexes of the individuals that played from the
+// fittest to the weakest, the ones that did not play are left out.
+func Ranked(pop []Individual) []int {
+	res := Played(pop)
+
+	sort.SliceStable(res, func(a, b int) bool {
+		return pop[res[a]].Fitness > pop[res[b]].Fitness
+	})
+
+	return res
+}
+
+// Select picks the index of a parent with the given strategy, among the
+// individuals that played.
+func Select(pop []Individual, strategy string, size int, r *rand.Rand) (int, error) {
+	played := Played(pop)
+
+	switch strategy {
+	case SELECTION_TOURNAMENT:
+		best := played[r.IntN(len(played))]
+		for range max(size, 1) - 1 {
+			i := played[r.IntN(len(played))]
+			if pop[i].Fitness > pop[best].Fitness {
+				best = i
+			}
+		}
+		return best, nil
+	case SELECTION_ROULETTE:
+		// Shift so the weakest still has a small chance, points can be
+		// negative
+		low := pop[played[0]].Fitness
+		for _, i := range played {
+			low = min(low, pop[i].Fitness)
+		}
+
+		total := 0
+		for _, i := range played {
+			total += pop[i].Fitness - low + 1
+		}
+
+		pick := r.IntN(total)
+		for _, i := range played {
+			pick -= pop[i].Fitness - low + 1
+			if pick < 0 {
+				return i, nil
+			}
+		}
+		return played[len(played)-1], nil
+	}
+
+	return 0, fmt.Errorf("unknown selection %q", strategy)
+}
+
+// layers returns the weights of every layer with its bias as the last row.
+// The rows share memory with the model.
+func (m *Model) layers() [][][]float64 {
+	res := [][][]float64{}
+	for _, l := range m.Layers {
+		res = append(res, append(append([][]float64{}, l.Weights...), l.Bias))
+	}
+
+	return res
+}
+
+// BreedModels crosses two models into two children, each gene the first
+// child takes from one parent the second takes from the other.
+func BreedModels(m1 Model, m2 Model, crossover string, r *rand.Rand) (Model, Model, error) {
+	if crossover != CROSSOVER_UNIFORM && crossover != CROSSOVER_LAYER {
+		return Model{}, Model{}, fmt.Errorf("unknown crossover %q", crossover)
+	}
+
+	c1, err := m1.Copy()
+	if err != nil {
+		return Model{}, Model{}, err
+	}
+	c2, err := m2.Copy()
+	if err != nil {
+		return Model{}, Model{}, err
+	}
+	c1.Points = 0
+	c2.Points = 0
+
+	l1 := c1.layers()
+	l2 := c2.layers()
+
+	if len(l1) != len(l2) {
+		return Model{}, Model{}, errors.New("Models do not match")
+	}
+
+	for i := range l1 {
+		if len(l1[i]) != len(l2[i]) {
+			return Model{}, Model{}, errors.New("Models do not match")
+		}
+
+		swapLayer := r.Float64() < 0.5
+
+		for j := range l1[i] {
+			if len(l1[i][j]) != len(l2[i][j]) {
+				return Model{}, Model{}, errors.New("Models do not match")
+			}
+
+			for k := range l1[i][j] {
+				swap := swapLayer
+				if crossover == CROSSOVER_UNIFORM {
+					swap = r.Float64() < 0.5
+				}
+
+				if swap {
+					l1[i][j][k], l2[i][j][k] = l2[i][j][k], l1[i][j][k]
+				}
+			}
+		}
+	}
+
+	return *c1, *c2, nil
+}
+
+// Breed builds the next generation: the elite is kept as it is and the
+// rest are children of selected parents, crossed and mutated.
+func Breed(pop []Individual, cfg TrainConfig, mu Mutation, r *rand.Rand) ([]Individual, error) {
+	next := []Individual{}
+
+	ranked := Ranked(pop)
+	for _, i := range ranked[:min(cfg.Elite, len(ranked))] {
+		m, err := pop[i].Model.Copy()
+		if err != nil {
+			return nil, err
+		}
+		next = append(next, Individual{Model: *m})
+	}
+
+	for len(next) < len(pop) {
+		a, err := Select(pop, cfg.Selection, cfg.TournamentSize, r)
+		if err != nil {
+			return nil, err
+		}
+		b, err := Select(pop, cfg.Selection, cfg.TournamentSize, r)
+		if err != nil {
+			return nil, err
+		}
+
+		c1, c2 := pop[a].Model, pop[b].Model
+		if r.Float64() < cfg.CrossoverRate {
+			c1, c2, err = BreedModels(pop[a].Model, pop[b].Model, cfg.Crossover, r)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			m1, err := c1.Copy()
+			if err != nil {
+				return nil, err
+			}
+			m2, err := c2.Copy()
+			if err != nil {
+				return nil, err
+			}
+			c1, c2 = *m1, *m2
+		}
+
+		for _, c := range []Model{c1, c2} {
+			if len(next) == len(pop) {
+				break
+			}
+			c.MutateWith(mu, r)
+			c.Points = 0
+			next = append(next, Individual{
+				Model:         c,
+				Child:         true,
+				ParentFitness: max(pop[a].Fitness, pop[b].Fitness),
+			})
+		}
+	}
+
+	return next, nil
+}
@@ -0,0 +1,254 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"text/tabwriter"
+)
+
+const LADDER_FILE = "ladder.json"
+const ELO_START = 1500.0
+const ELO_K = 32.0
+
+// Opponents a new model plays to be placed on the ladder, the ones with the
+// closest rating are picked
+const LADDER_OPPONENTS = 4
+
+type Rating struct {
+	Model string
+	// Hash of the model file the rating was earned with
+	Hash   string
+	Elo    float64
+	Games  int
+	Wins   int
+	Losses int
+	Draws  int
+}
+
+// Ladder keeps an Elo rating per model file across runs.
+type Ladder struct {
+	Ratings map[string]*Rating
+}
+
+// LoadLadder reads the ladder file, a missing file is an empty ladder.
+func LoadLadder(file string) (*Ladder, error) {
+	l := &Ladder{Ratings: map[string]*Rating{}}
+
+	b, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, l); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if l.Ratings == nil {
+		l.Ratings = map[string]*Rating{}
+	}
+
+	return l, nil
+}
+
+func (l *Ladder) Save(file string) error {
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+// Get returns the rating of a model, adding it with the starting rating
+// when it is not on the ladder yet.
+func (l *Ladder) Get(model string) *Rating {
+	model = filepath.Clean(model)
+
+	r, ok := l.Ratings[model]
+	if !ok {
+		r = &Rating{Model: model, Elo: ELO_START}
+		l.Ratings[model] = r
+	}
+
+	return r
+}
+
+// Track ties the rating of a model file to its content. A file written
+// over with another model starts again from the starting rating, entries
+// without a hash take the one of the current file.
+func (l *Ladder) Track(file string) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(b)
+	hash := hex.EncodeToString(sum[:])
+
+	r := l.Get(file)
+	if r.Hash != "" && r.Hash != hash {
+		*r = Rating{Model: r.Model, Elo: ELO_START}
+	}
+	r.Hash = hash
+
+	return nil
+}
+
+// Record updates both ratings with the result of a game.
+func (l *Ladder) Record(blue string, red string, winner PLAYER_TYPE, draw bool) {
+	b, r := l.Get(blue), l.Get(red)
+
+	score := 0.0
+	switch {
+	case draw:
+		score = 0.5
+		b.Draws++
+		r.Draws++
+	case winner == BLUE:
+		score = 1
+		b.Wins++
+		r.Losses++
+	default:
+		r.Wins++
+		b.Losses++
+	}
+
+	expected := 1 / (1 + math.Pow(10, (r.Elo-b.Elo)/400))
+	delta := ELO_K * (score - expected)
+
+	b.Elo += delta
+	r.Elo -= delta
+	b.Games++
+	r.Games++
+}
+
+// RecordTrain records a played Train between two model files.
+func (l *Ladder) RecordTrain(blue string, red string, t Train) {
+	l.Record(blue, red, t.Winner, t.Game.Draw)
+}
+
+// Standings returns the ratings, best first.
+func (l *Ladder) Standings() []Rating {
+	res := []Rating{}
+	for _, r := range l.Ratings {
+		res = append(res, *r)
+	}
+
+	sort.Slice(res, func(a, b int) bool {
+		if res[a].Elo != res[b].Elo {
+			return res[a].Elo > res[b].Elo
+		}
+		return res[a].Model < res[b].Model
+	})
+
+	return res
+}
+
+func (l *Ladder) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "#\tModel\tElo\tGames\tWins\tLosses\tDraws\t")
+	for i, r := range l.Standings() {
+		fmt.Fprintf(tw, "%d\t%s\t%.0f\t%d\t%d\t%d\t%d\t\n", i+1, r.Model, r.Elo, r.Games, r.Wins, r.Losses, r.Draws)
+	}
+
+	return tw.Flush()
+}
+
+// UpdateLadder loads the ladder file, applies fn and saves it back.
+func UpdateLadder(file string, fn func(l *Ladder) error) error {
+	l, err := LoadLadder(file)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(l); err != nil {
+		return err
+	}
+
+	return l.Save(file)
+}
+
+// Place plays a model against the closest rated models of the ladder, one
+// game with each colour per opponent and the given war rules, and records
+// the results. A model alone on the ladder keeps the starting rating.
+func (l *Ladder) Place(file string, seed uint64, workers int, rules WarRules) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	model, err := LoadModel(file)
+	if err != nil {
+		return err
+	}
+
+	if err := l.Track(file); err != nil {
+		return err
+	}
+
+	me := l.Get(file)
+	opponents := []Rating{}
+	for _, r := range l.Standings() {
+		if r.Model == me.Model {
+			continue
+		}
+		if err := l.Track(r.Model); err != nil {
+			continue
+		}
+
+		opponents = append(opponents, *l.Get(r.Model))
+	}
+
+	sort.SliceStable(opponents, func(a, b int) bool {
+		return math.Abs(opponents[a].Elo-me.Elo) < math.Abs(opponents[b].Elo-me.Elo)
+	})
+	opponents = opponents[:min(len(opponents), LADDER_OPPONENTS)]
+
+	files := []string{me.Model}
+	models := []Model{model}
+	for _, o := range opponents {
+		m, err := LoadModel(o.Model)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, o.Model)
+		models = append(models, m)
+	}
+
+	rng := NewRand(seed)
+	games := []TournamentGame{}
+	for i := 1; i < len(models); i++ {
+		s := rng.Uint64()
+		games = append(games, newTournamentGame(models, 0, i, s, rules), newTournamentGame(models, i, 0, s, rules))
+	}
+
+	trains := make([]Train, len(games))
+	for i, tg := range games {
+		trains[i] = tg.Train
+	}
+	PlayTrains(trains, workers)
+
+	for i, tg := range games {
+		l.RecordTrain(files[tg.Blue], files[tg.Red], trains[i])
+	}
+
+	return nil
+}
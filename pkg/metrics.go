@@ -0,0 +1,252 @@
+package pkg
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Metrics formats
+const METRICS_CSV = "csv"
+const METRICS_JSONL = "jsonl"
+
+// Metrics files, written to the training output directory with the format
+// as extension
+const MATCH_METRICS_FILE = "matches"
+const GENERATION_METRICS_FILE = "generations"
+
+// MatchMetrics is how a single training match ended.
+type MatchMetrics struct {
+	Generation int
+	Game       int
+	Seed       uint64
+	// Population index of each side
+	Blue       int
+	Red        int
+	Winner     PLAYER_TYPE
+	Draw       bool
+	Ticks      int
+	BluePoints int
+	RedPoints  int
+	BlueTech   int
+	RedTech    int
+	BlueMining int
+	RedMining  int
+	// Units bought over the whole match
+	BlueUnits int
+	RedUnits  int
+}
+
+// GenerationMetrics sums up the matches of a generation.
+type GenerationMetrics struct {
+	Generation  int
+	Best        int
+	Mean        int
+	Worst       int
+	Sigma       float64
+	BlueWinRate float64
+	RedWinRate  float64
+	DrawRate    float64
+	AvgTicks    float64
+	// Averages over both sides of every match
+	AvgTech   float64
+	AvgMining float64
+	AvgUnits  float64
+}
+
+func NewMatchMetrics(gen int, game int, p Pairing, t Train) MatchMetrics {
+	blue := t.Game.GetPlayerById(BLUE)
+	red := t.Game.GetPlayerById(RED)
+
+	return MatchMetrics{
+		Generation: gen,
+		Game:       game,
+		Seed:       t.Seed,
+		Blue:       p.Blue,
+		Red:        p.Red,
+		Winner:     t.Winner,
+		Draw:       t.Game.Draw,
+		Ticks:      t.Game.Tick,
+		BluePoints: t.BluePoints,
+		RedPoints:  t.RedPoints,
+		BlueTech:   blue.TechnologyLevel,
+		RedTech:    red.TechnologyLevel,
+		BlueMining: blue.MiningLevel,
+		RedMining:  red.MiningLevel,
+		BlueUnits:  len(t.Game.GetUnitiesByPlayerId(BLUE)),
+		RedUnits:   len(t.Game.GetUnitiesByPlayerId(RED)),
+	}
+}
+
+func NewGenerationMetrics(stats GenerationStats, matches []MatchMetrics) GenerationMetrics {
+	res := GenerationMetrics{
+		Generation: stats.Generation,
+		Best:       stats.Best,
+		Mean:       stats.Mean,
+		Worst:      stats.Worst,
+		Sigma:      stats.Sigma,
+	}
+	if len(matches) == 0 {
+		return res
+	}
+
+	for _, m := range matches {
+		switch {
+		case m.Draw:
+			res.DrawRate++
+		case m.Winner == BLUE:
+			res.BlueWinRate++
+		case m.Winner == RED:
+			res.RedWinRate++
+		}
+
+		res.AvgTicks += float64(m.Ticks)
+		res.AvgTech += float64(m.BlueTech + m.RedTech)
+		res.AvgMining += float64(m.BlueMining + m.RedMining)
+		res.AvgUnits += float64(m.BlueUnits + m.RedUnits)
+	}
+
+	n := float64(len(matches))
+	res.BlueWinRate /= n
+	res.RedWinRate /= n
+	res.DrawRate /= n
+	res.AvgTicks /= n
+	res.AvgTech /= 2 * n
+	res.AvgMining /= 2 * n
+	res.AvgUnits /= 2 * n
+
+	return res
+}
+
+func (m MatchMetrics) header() []string {
+	return []string{"generation", "game", "seed", "blue", "red", "winner", "draw", "ticks", "blue_points", "red_points", "blue_tech", "red_tech", "blue_mining", "red_mining", "blue_units", "red_units"}
+}
+
+func (m MatchMetrics) row() []string {
+	return []string{
+		strconv.Itoa(m.Generation),
+		strconv.Itoa(m.Game),
+		strconv.FormatUint(m.Seed, 10),
+		strconv.Itoa(m.Blue),
+		strconv.Itoa(m.Red),
+		string(m.Winner),
+		strconv.FormatBool(m.Draw),
+		strconv.Itoa(m.Ticks),
+		strconv.Itoa(m.BluePoints),
+		strconv.Itoa(m.RedPoints),
+		strconv.Itoa(m.BlueTech),
+		strconv.Itoa(m.RedTech),
+		strconv.Itoa(m.BlueMining),
+		strconv.Itoa(m.RedMining),
+		strconv.Itoa(m.BlueUnits),
+		strconv.Itoa(m.RedUnits),
+	}
+}
+
+func (m GenerationMetrics) header() []string {
+	return []string{"generation", "best", "mean", "worst", "sigma", "blue_win_rate", "red_win_rate", "draw_rate", "avg_ticks", "avg_tech", "avg_mining", "avg_units"}
+}
+
+func (m GenerationMetrics) row() []string {
+	f := func(v float64) string {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+
+	return []string{
+		strconv.Itoa(m.Generation),
+		strconv.Itoa(m.Best),
+		strconv.Itoa(m.Mean),
+		strconv.Itoa(m.Worst),
+		f(m.Sigma),
+		f(m.BlueWinRate),
+		f(m.RedWinRate),
+		f(m.DrawRate),
+		f(m.AvgTicks),
+		f(m.AvgTech),
+		f(m.AvgMining),
+		f(m.AvgUnits),
+	}
+}
+
+type metricsRecord interface {
+	header() []string
+	row() []string
+}
+
+// MetricsWriter appends match and generation metrics to their files.
+type MetricsWriter struct {
+	format      string
+	matches     *os.File
+	generations *os.File
+}
+
+// NewMetricsWriter creates the metrics files in dir. When resuming rows are
+// appended to the ones already there so the training keeps its history.
+func NewMetricsWriter(dir string, format string, resume bool) (*MetricsWriter, error) {
+	if format != METRICS_CSV && format != METRICS_JSONL {
+		return nil, fmt.Errorf("unknown metrics format %q", format)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	open := func(name string) (*os.File, error) {
+		return os.OpenFile(filepath.Join(dir, name+"."+format), flags, 0644)
+	}
+
+	matches, err := open(MATCH_METRICS_FILE)
+	if err != nil {
+		return nil, err
+	}
+
+	generations, err := open(GENERATION_METRICS_FILE)
+	if err != nil {
+		matches.Close()
+		return nil, err
+	}
+
+	return &MetricsWriter{format: format, matches: matches, generations: generations}, nil
+}
+
+func (w *MetricsWriter) WriteMatch(m MatchMetrics) error {
+	return w.write(w.matches, m)
+}
+
+func (w *MetricsWriter) WriteGeneration(m GenerationMetrics) error {
+	return w.write(w.generations, m)
+}
+
+func (w *MetricsWriter) write(f *os.File, r metricsRecord) error {
+	if w.format == METRICS_JSONL {
+		return json.NewEncoder(f).Encode(r)
+	}
+
+	// The header goes on top of a new file only
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	c := csv.NewWriter(f)
+	if info.Size() == 0 {
+		c.Write(r.header())
+	}
+	c.Write(r.row())
+	c.Flush()
+
+	return c.Error()
+}
+
+func (w *MetricsWriter) Close() error {
+	err := w.matches.Close()
+	if gErr := w.generations.Close(); err == nil {
+		err = gErr
+	}
+
+	return err
+}
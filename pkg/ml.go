@@ -6,63 +6,36 @@ import (
 	"math"
 	"math/rand/v2"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
 	"time"
 )
 
-// Input Layer
-// Time -> w0
-// Coins -> w1
-// Technology Level -> w2
-// Tech Update Cost -> w3
-// Mining Level -> w4
-// Mining Update Cost -> w5
-// My Unities -> w6
-// Enemy Unities -> w7
-// Unity Cost -> w8
-
-// Output Layer
-// Upgrade Tech -> w0
-// Upgrade Mining -> w1
-// Buy Unity -> w2
-func (e GameUpdateEvent) ToInput() [INPUT_SIZE]float64 {
-	normalizedTime := float64(e.Time) / (5 * time.Minute.Seconds())
-	micron := 100.0
-
-	return [INPUT_SIZE]float64{
-		normalizedTime,
-		float64(e.Coins) / micron,
-		float64(e.TechLevel) / 3,
-		float64(e.TechUpdateCost) / micron,
-		float64(e.MiningLevel) / 3,
-		float64(e.MiningUpdateCost) / micron,
-		float64(e.Unities / 100),
-		float64(e.EnemyUnities / 100),
-		float64(e.UnityCost) / micron,
-	}
+// ToInput is the default observation, see INPUT_NAMES.
+func (e GameUpdateEvent) ToInput() Input {
+	return e.Observe(INPUT_NAMES)
 }
 
-const INPUT_SIZE = 9
 const HIDDEN_LAYER = 12
 const HIDDEN_LAYER_2 = 5
 const OUTPUT_SIZE = 3
 
-type Input [INPUT_SIZE]float64
-type Output [OUTPUT_SIZE]float64
+type Input []float64
+type Output []float64
 
 type Model struct {
-	Type PLAYER_TYPE
-	// Input
-	W_I_H1 [][]float64
-
-	// Hidden 1
-	W_H1_H2 [][]float64
-	B_H1    []float64
-	// Hidden 2
-	W_H2_O [][]float64
-	B_H2   []float64
-	// Output
-	B_O    []float64
-	Points int
+	Version int
+	Type    PLAYER_TYPE
+	// Features the network reads, see FEATURES, and what it outputs, see
+	// FULL_OUTPUTS
+	Inputs  []string
+	Outputs []string
+	// From the first hidden layer to the output one
+	Layers     []Layer
+	Points     int
+	Provenance *Provenance `json:",omitempty"`
 }
 
 type BatchMap struct {
@@ -77,273 +50,510 @@ type Train struct {
 	ModelBlue    *Model
 	Winner       PLAYER_TYPE
 	WinnerPoints int
+	// Score of each side, FITNESS_POINTS is used when Fitness is nil
+	Fitness    Fitness
+	BluePoints int
+	RedPoints  int
+	Seed       uint64
+	Replay     *Replay
 }
 
 const GENERATIONS = 10
 const GAME_PER_GEN = 10
+const MUTATION_RATE = 0.3
+const TRAIN_DIR = "./train/"
+const BEST_MODEL_FILE = "best.json"
+
+// TrainConfig zero values fall back to the defaults above, but for the
+// rates where 0 is a setting and only negative ones fall back.
+type TrainConfig struct {
+	// Seed for models, mutations and games, a random one is picked when zero
+	Seed uint64
+	// Matches played at the same time, defaults to the number of CPUs
+	Workers     int
+	Generations int
+	// Matches played per generation, spread evenly over the population
+	GamesPerGen int
+	// Chance of mutating each weight, 0 turns mutation off
+	MutationRate float64
+	// MUTATION_RESET or MUTATION_GAUSSIAN
+	Mutation string
+	// Mutation rate of each layer from the input one, MutationRate is used
+	// for the rest
+	LayerRates []float64
+	// Start sigma of the gaussian mutation
+	Sigma float64
+	// How sigma changes over the generations, ADAPT_NONE, ADAPT_DECAY or
+	// ADAPT_ONE_FIFTH
+	Adapt string
+	// Layers of new models, DEFAULT_TOPOLOGY when empty
+	Topology []LayerSpec
+	// Features new models read, INPUT_NAMES when empty
+	Observation []string
+	// Outputs new models write, OUTPUT_NAMES when empty
+	Outputs []string
+	// Actions allowed in the games once the border is down
+	WarRules WarRules
+	// Models in every generation
+	Population int
+	// Fittest models carried over unchanged to the next generation
+	Elite int
+	// Parent selection, SELECTION_TOURNAMENT or SELECTION_ROULETTE
+	Selection      string
+	TournamentSize int
+	// Crossover, CROSSOVER_UNIFORM or CROSSOVER_LAYER
+	Crossover string
+	// Chance of two parents being crossed instead of copied, 0 turns
+	// crossover off
+	CrossoverRate float64
+	// Directory the model of every generation is written to
+	OutputDir string
+	// File the best model is written to at the end
+	BestFile string
+	// Write the replay of every game to OutputDir/replays
+	Record bool
+	// Fitness the models are scored with, one of FITNESS
+	Fitness string
+	// Ladder file the model of every generation is placed on, off when
+	// empty
+	Ladder string
+	// Write match and generation metrics to OutputDir as METRICS_CSV or
+	// METRICS_JSONL, off when empty
+	Metrics string
+	// Go on from the checkpoint in OutputDir with its config, only Workers
+	// is taken from this one
+	Resume bool
+}
+
+func RunTrain(cfg TrainConfig) error {
+	var resume *Checkpoint
+	if cfg.Resume {
+		dir := cfg.OutputDir
+		if dir == "" {
+			dir = TRAIN_DIR
+		}
+
+		c, err := LoadCheckpoint(filepath.Join(dir, CHECKPOINT_FILE))
+		if err != nil {
+			return err
+		}
+
+		workers := cfg.Workers
+		cfg = c.Config
+		cfg.Workers = workers
+		resume = &c
+	}
+
+	if cfg.Seed == 0 {
+		cfg.Seed = NewSeed()
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.Generations <= 0 {
+		cfg.Generations = GENERATIONS
+	}
+	if cfg.GamesPerGen <= 0 {
+		cfg.GamesPerGen = GAME_PER_GEN
+	}
+	if cfg.MutationRate < 0 {
+		cfg.MutationRate = MUTATION_RATE
+	}
+	if cfg.Mutation == "" {
+		cfg.Mutation = MUTATION_RESET
+	}
+	if cfg.Sigma <= 0 {
+		cfg.Sigma = MUTATION_SIGMA
+	}
+	if cfg.Adapt == "" {
+		cfg.Adapt = ADAPT_NONE
+	}
+	mutation := Mutation{
+		Kind:       cfg.Mutation,
+		Rate:       cfg.MutationRate,
+		LayerRates: cfg.LayerRates,
+		Sigma:      cfg.Sigma,
+	}
+	if err := mutation.Validate(); err != nil {
+		return err
+	}
+	if _, err := AdaptSigma(cfg.Adapt, cfg.Sigma, cfg.Sigma, 0, 0); err != nil {
+		return err
+	}
+	if len(cfg.Outputs) == 0 {
+		cfg.Outputs = OUTPUT_NAMES
+	}
+	if err := ValidateOutputs(cfg.Outputs); err != nil {
+		return err
+	}
+	if len(cfg.Topology) == 0 {
+		// The default hidden layers with an output for each action
+		cfg.Topology = slices.Clone(DEFAULT_TOPOLOGY)
+		cfg.Topology[len(cfg.Topology)-1].Size = len(cfg.Outputs)
+	}
+	if err := ValidateTopology(cfg.Topology); err != nil {
+		return err
+	}
+	if out := cfg.Topology[len(cfg.Topology)-1].Size; out != len(cfg.Outputs) {
+		return fmt.Errorf("output layer has size %d, expected %d for %d outputs", out, len(cfg.Outputs), len(cfg.Outputs))
+	}
+	if len(cfg.Observation) == 0 {
+		cfg.Observation = INPUT_NAMES
+	}
+	if err := ValidateObservation(cfg.Observation); err != nil {
+		return err
+	}
+	if cfg.Population < 2 {
+		cfg.Population = POPULATION_SIZE
+	}
+	if cfg.Elite < 0 {
+		cfg.Elite = 0
+	}
+	if cfg.Selection == "" {
+		cfg.Selection = SELECTION_TOURNAMENT
+	}
+	if cfg.TournamentSize <= 0 {
+		cfg.TournamentSize = TOURNAMENT_SIZE
+	}
+	if cfg.Crossover == "" {
+		cfg.Crossover = CROSSOVER_UNIFORM
+	}
+	if cfg.CrossoverRate < 0 {
+		cfg.CrossoverRate = CROSSOVER_RATE
+	}
+	if cfg.Selection != SELECTION_TOURNAMENT && cfg.Selection != SELECTION_ROULETTE {
+		return fmt.Errorf("unknown selection %q", cfg.Selection)
+	}
+	if cfg.Crossover != CROSSOVER_UNIFORM && cfg.Crossover != CROSSOVER_LAYER {
+		return fmt.Errorf("unknown crossover %q", cfg.Crossover)
+	}
+	if cfg.Fitness == "" {
+		cfg.Fitness = FITNESS_POINTS
+	}
+	fitness, err := GetFitness(cfg.Fitness)
+	if err != nil {
+		return err
+	}
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = TRAIN_DIR
+	}
+	if cfg.BestFile == "" {
+		cfg.BestFile = BEST_MODEL_FILE
+	}
+	fmt.Println("TRAIN SEED:", cfg.Seed, "WORKERS:", cfg.Workers, "POPULATION:", cfg.Population, "TOPOLOGY:", FormatTopology(cfg.Topology), "INPUTS:", len(cfg.Observation), "OUTPUTS:", len(cfg.Outputs), "FITNESS:", cfg.Fitness, "WAR RULES:", cfg.WarRules)
+
+	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+		return err
+	}
+
+	var metrics *MetricsWriter
+	if cfg.Metrics != "" {
+		metrics, err = NewMetricsWriter(cfg.OutputDir, cfg.Metrics, resume != nil)
+		if err != nil {
+			return err
+		}
+		defer metrics.Close()
+	}
+
+	replayDir := filepath.Join(cfg.OutputDir, "replays")
+	if cfg.Record {
+		if err := os.MkdirAll(replayDir, 0755); err != nil {
+			return err
+		}
+	}
+
+	// Same as NewRand, the source is kept so it can be checkpointed
+	src := rand.NewPCG(cfg.Seed, cfg.Seed)
+	rng := rand.New(src)
+
+	state := Checkpoint{
+		Version: CHECKPOINT_VERSION,
+		Config:  cfg,
+		Best:    Individual{Fitness: math.MinInt},
+		Sigma:   cfg.Sigma,
+	}
+	if resume != nil {
+		state = *resume
+		state.Config = cfg
+		if err := src.UnmarshalBinary(state.Rng); err != nil {
+			return fmt.Errorf("checkpoint random source: %w", err)
+		}
+		fmt.Println("RESUMING GEN:", state.Generation)
+	} else {
+		state.Population = NewPopulation(cfg.Population, cfg.Observation, cfg.Outputs, cfg.Topology, rng)
+	}
 
-func RunTrain() {
-	trains := map[int][]Train{}
-	currentBetter := Model{}
+	pop := state.Population
+	best := state.Best
+	mutation.Sigma = state.Sigma
 
-	for gen := range GENERATIONS {
-		// 10 ROUNDS
-		for j := range GAME_PER_GEN {
+	for gen := state.Generation; gen < cfg.Generations; gen++ {
+		// Everything random is drawn here, before the matches run, so the
+		// generation is the same whatever order the workers finish in
+		pairings := PairPopulation(len(pop), cfg.GamesPerGen, rng)
+		trains := []Train{}
+
+		for j, p := range pairings {
 			// Prepare Game
 			g := NewGame(CreateGameArgs{
-				Speed: 32,
+				Speed:    32,
+				Seed:     rng.Uint64(),
+				WarRules: cfg.WarRules,
 			})
 			g.Init()
 
-			// Neural Network Blue
-			var mB Model
-			// Neural Network Red
-			var mR Model
-
-			if currentBetter.Points == 0 {
-				fmt.Println("Generating Random Models")
-				mB = Model{Type: g.PlayerBlue.Id}
-				mR = Model{Type: g.PlayerRed.Id}
+			// Every game gets its own copies, the same model can play
+			// several games at once
+			mB, _ := pop[p.Blue].Model.Copy()
+			mB.Type = BLUE
+			mR, _ := pop[p.Red].Model.Copy()
+			mR.Type = RED
 
-				mB.InitRandom()
-				mR.InitRandom()
+			t := Train{
+				Id:        gen*cfg.GamesPerGen + j,
+				ModelRed:  mR,
+				ModelBlue: mB,
+				Game:      &g,
+				Seed:      g.Seed,
+				Fitness:   fitness,
+			}
+			if cfg.Record {
+				t.Replay = g.Record()
 			}
 
-			if currentBetter.Type != "" {
-				// BLUE
-				m, _ := currentBetter.Copy()
-				mB = *m
+			trains = append(trains, t)
+		}
 
-				// RED
-				m, _ = currentBetter.Copy()
-				mR = *m
-				mR.Mutate(0.3)
-			}
+		PlayTrains(trains, cfg.Workers)
 
-			t := Train{
-				Id:        gen + j,
-				ModelRed:  &mR,
-				ModelBlue: &mB,
-				Game:      &g,
-			}
+		matches := []MatchMetrics{}
+		for j, t := range trains {
+			p := pairings[j]
+			pop[p.Blue].Games++
+			pop[p.Blue].Points += t.BluePoints
+			pop[p.Red].Games++
+			pop[p.Red].Points += t.RedPoints
+
+			fmt.Println("TRAIN GEN:", gen, "GAME:", j, "BLUE:", p.Blue, "RED:", p.Red, "WINNER:", t.Winner, "SEED:", t.Seed, "BLUE POINTS:", t.BluePoints, "RED POINTS:", t.RedPoints)
 
-			// Subs to Event
-			Bus.Subscribe(fmt.Sprint("game:", g.ID, "/update"), func(e GameUpdateEvent) {
-				actions := mB.HandleUpdate(e)
-				for _, a := range actions {
-					g.HandleActionEvent(ActionEvent{
-						Owner:  BLUE,
-						Action: a,
-					})
+			m := NewMatchMetrics(gen, j, p, t)
+			matches = append(matches, m)
+			if metrics != nil {
+				if err := metrics.WriteMatch(m); err != nil {
+					return err
 				}
-			})
-			Bus.Subscribe(fmt.Sprint("game:", g.ID, "/update"), func(e GameUpdateEvent) {
-				actions := mR.HandleUpdate(e)
-				for _, a := range actions {
-					g.HandleActionEvent(ActionEvent{
-						Owner:  RED,
-						Action: a,
-					})
+			}
+
+			if t.Replay != nil {
+				file := filepath.Join(replayDir, fmt.Sprint("gen-", gen, "-game-", j, ".replay"))
+				if err := WriteReplay(t.Replay, file); err != nil {
+					return err
 				}
-			})
+			}
+		}
 
-			fmt.Println("TRAIN GEN:", gen, "GAME: ", j, "STARTED")
-			winner := RunGame(&g)
+		Score(pop)
+		ranked := Ranked(pop)
+		genBest := pop[ranked[0]]
 
-			t.Winner = winner
+		stats := GenerationStats{
+			Generation: gen,
+			Best:       genBest.Fitness,
+			Worst:      pop[ranked[len(ranked)-1]].Fitness,
+			Sigma:      mutation.Sigma,
+		}
+		for _, i := range ranked {
+			stats.Mean += pop[i].Fitness
+		}
+		stats.Mean /= len(ranked)
 
-			points := t.GetWinnerPoints()
-			t.WinnerPoints = points
+		fmt.Println("TRAIN GEN FINISHED:", gen, "BEST:", stats.Best, "MEAN:", stats.Mean, "WORST:", stats.Worst, "SIGMA:", stats.Sigma)
 
-			if winner == BLUE {
-				mB.Points = points
-			} else {
-				mR.Points = points
+		if metrics != nil {
+			if err := metrics.WriteGeneration(NewGenerationMetrics(stats, matches)); err != nil {
+				return err
 			}
+		}
 
-			trains[gen] = append(trains[gen], t)
-			fmt.Println("TRAIN GEN:", gen, "GAME: ", j, "WINNER: ", winner, "TRAIN", t, "POINTS:", points)
+		genBest.Model.Provenance = &Provenance{
+			Seed:       cfg.Seed,
+			Generation: gen,
+			Fitness:    cfg.Fitness,
+			Points:     genBest.Fitness,
+			Date:       time.Now().UTC(),
 		}
 
-		fmt.Println("TRAIN GEN FINISHED:", gen, trains[gen])
+		if genBest.Fitness > best.Fitness {
+			fmt.Println("CURRENT UPDATED", genBest.Fitness)
+			best = genBest
+		}
 
-		for _, v := range trains[gen] {
-			if v.WinnerPoints > currentBetter.Points {
-				fmt.Println("CURRENT UPDATED", v.WinnerPoints)
-				winner := func() Model {
-					if v.Winner == BLUE {
-						return *v.ModelBlue
-					}
-					return *v.ModelRed
-				}()
+		genFile := filepath.Join(cfg.OutputDir, fmt.Sprint("gen-", gen, ".json"))
+		if err := WriteModel(genBest.Model, genFile); err != nil {
+			return err
+		}
 
-				currentBetter = winner
-				continue
+		if cfg.Ladder != "" {
+			// Own seed so the ladder does not change the training games
+			err := UpdateLadder(cfg.Ladder, func(l *Ladder) error {
+				return l.Place(genFile, cfg.Seed+uint64(gen), cfg.Workers, cfg.WarRules)
+			})
+			if err != nil {
+				return err
 			}
 		}
 
-		WriteModel(currentBetter, fmt.Sprint("./train/", "gen-", gen, ".json"))
-	}
+		if gen < cfg.Generations-1 {
+			progress := float64(gen+1) / float64(max(cfg.Generations-1, 1))
+			mutation.Sigma, err = AdaptSigma(cfg.Adapt, cfg.Sigma, mutation.Sigma, progress, SuccessRate(pop))
+			if err != nil {
+				return err
+			}
+
+			next, err := Breed(pop, cfg, mutation, rng)
+			if err != nil {
+				return err
+			}
+			pop = next
+		}
 
-	WriteModel(currentBetter, "best.json")
+		state.Generation = gen + 1
+		state.Population = pop
+		state.Best = best
+		state.Sigma = mutation.Sigma
+		state.History = append(state.History, stats)
+		state.Rng, err = src.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := WriteCheckpoint(state, filepath.Join(cfg.OutputDir, CHECKPOINT_FILE)); err != nil {
+			return err
+		}
+	}
 
-	fmt.Println(trains)
+	return WriteModel(best.Model, cfg.BestFile)
 }
 
-func WriteModel(m Model, file string) {
-	fmt.Println("WRITTING CURRENT")
-	res, err := json.Marshal(m)
-	if err != nil {
-		fmt.Errorf(err.Error())
+// PlayTrains plays every match on a pool of workers and waits for all of
+// them. Each worker only writes to the Train it picked up.
+func PlayTrains(trains []Train, workers int) {
+	jobs := make(chan *Train)
+	var wg sync.WaitGroup
+
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				t.Play()
+			}
+		}()
 	}
 
-	err = os.WriteFile(file, res, 0644)
-	if err != nil {
-		fmt.Errorf(err.Error())
+	for i := range trains {
+		jobs <- &trains[i]
 	}
+	close(jobs)
+
+	wg.Wait()
 }
 
-func (t *Train) GetWinnerPoints() int {
-	enemyId := func() PLAYER_TYPE {
-		if t.Winner == BLUE {
-			return RED
-		}
-		return BLUE
-	}()
+// Play runs the match headless with each model playing its side.
+func (t *Train) Play() {
+	t.Winner = RunMatch(t.Game, map[PLAYER_TYPE]Agent{
+		BLUE: t.ModelBlue,
+		RED:  t.ModelRed,
+	}, nil)
 
-	winner := t.Game.GetPlayerById(t.Winner)
-	loser := t.Game.GetPlayerById(enemyId)
+	f := t.Fitness
+	if f == nil {
+		f = FITNESS[FITNESS_POINTS]
+	}
+	t.BluePoints = f.Score(t.Game, BLUE)
+	t.RedPoints = f.Score(t.Game, RED)
+	t.WinnerPoints = t.GetWinnerPoints()
+}
 
-	totalUnities := len(t.Game.GetUnitiesByPlayerId(winner.Id))
-	// aliveUnits := len(t.Game.GetAliveUnitiesByPlayerId(winner.Id))
-	enemyTotalUnities := len(t.Game.GetUnitiesByPlayerId(loser.Id))
+func LoadModel(file string) (Model, error) {
+	var m Model
 
-	techLevel := winner.TechnologyLevel
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return m, err
+	}
 
-	miningLevel := winner.MiningLevel
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", file, err)
+	}
 
-	if totalUnities == 0 {
-		return 0
+	if err := m.Validate(); err != nil {
+		return m, fmt.Errorf("%s: %w", file, err)
 	}
 
-	return enemyTotalUnities*50 + totalUnities*100 + techLevel*1000 + miningLevel*1000 + int(winner.TotalCoins/2) - winner.Coins*10
+	return m, nil
 }
 
-// Initiate all weights with random numbers from
-// -1.0 to 1.0
-func (m *Model) InitRandom() {
-	// Input
-	for range HIDDEN_LAYER {
-		// W I -> H1 [12,9]
-		w := []float64{}
-		for range INPUT_SIZE {
-			// Append [9]float
-			w = append(w, randomFloat(-1.0, 1.0))
-		}
-		// [12][9]float64
-		m.W_I_H1 = append(m.W_I_H1, w)
-		m.B_H1 = append(m.B_H1, randomFloat(-1.0, 1.0))
+func WriteModel(m Model, file string) error {
+	fmt.Println("WRITTING CURRENT")
+	res, err := json.Marshal(m)
+	if err != nil {
+		return err
 	}
 
-	for range HIDDEN_LAYER_2 {
-		// W H1 -> 2 [5, 12]
-		w := []float64{}
-		for range HIDDEN_LAYER {
-			// [12]float
-			w = append(w, randomFloat(-1.0, 1.0))
-		}
-		// [5][12]float
-		m.W_H1_H2 = append(m.W_H1_H2, w)
-		m.B_H2 = append(m.B_H2, randomFloat(-1.0, 1.0))
-	}
-
-	// Hidden 2
-	for range OUTPUT_SIZE {
-		// W H2 -> O [5, 3]
-		w := []float64{}
-		for range HIDDEN_LAYER_2 {
-			// [5]float
-			w = append(w, randomFloat(-1.0, 1.0))
-		}
-		// [5][3]float
-		m.W_H2_O = append(m.W_H2_O, w)
-		m.B_O = append(m.B_O, randomFloat(-1.0, 1.0))
-	}
+	return os.WriteFile(file, res, 0644)
 }
 
-func (m *Model) HandleUpdate(e GameUpdateEvent) []ACTION {
-	if e.Owner != m.Type {
-		return []ACTION{}
+func (t *Train) GetWinnerPoints() int {
+	if t.Winner == BLUE {
+		return t.BluePoints
 	}
-
-	input := e.ToInput()
-
-	out := m.Result(input)
-
-	return OutToAction(out)
+	return t.RedPoints
 }
 
-func OutToAction(out Output) []ACTION {
-	res := []ACTION{}
+// PlayerPoints scores how well a player did, the more units, levels and
+// coins spent the better.
+func (g *Game) PlayerPoints(id PLAYER_TYPE) int {
+	player := g.GetPlayerById(id)
+	enemy := g.GetPlayerById(enemyOf(id))
 
-	if step(out[2]) == 1 {
-		res = append(res, UPDATE_TECH)
-	}
+	totalUnities := len(g.GetUnitiesByPlayerId(player.Id))
+	// aliveUnits := len(g.GetAliveUnitiesByPlayerId(player.Id))
+	enemyTotalUnities := len(g.GetUnitiesByPlayerId(enemy.Id))
 
-	if step(out[1]) == 1 {
-		res = append(res, UPDATE_MINING)
-	}
+	techLevel := player.TechnologyLevel
+
+	miningLevel := player.MiningLevel
 
-	if step(out[0]) == 1 {
-		res = append(res, BUY_SOLDIER)
+	if totalUnities == 0 {
+		return 0
 	}
 
-	return res
+	return enemyTotalUnities*50 + totalUnities*100 + techLevel*1000 + miningLevel*1000 + int(player.TotalCoins/2) - player.Coins*10
 }
 
-func (m Model) Result(input Input) Output {
-	// Input -> H1 Layer
-	var l0 [HIDDEN_LAYER]float64
-	for i, n := range m.W_I_H1 {
-		for j, in := range input {
-			l0[i] += in * n[j]
-		}
-
-		l0[i] += m.B_H1[i]
-		l0[i] = math.Tanh(l0[i])
+func (m *Model) HandleUpdate(e GameUpdateEvent) []ACTION {
+	if e.Owner != m.Type {
+		return []ACTION{}
 	}
 
-	// HIDDEN 1 -> H2
-	var h1 [HIDDEN_LAYER_2]float64
-	for i, n := range m.W_H1_H2 {
-		for j, val := range l0 {
-			h1[i] += n[j] * val
-		}
-
-		h1[i] += m.B_H2[i]
-		h1[i] = math.Tanh(h1[i])
-	}
+	input := e.Observe(m.Inputs)
 
-	// HIDDEN 2 -> Out
-	var out [OUTPUT_SIZE]float64
-	for i, n := range m.W_H2_O {
-		for j, val := range h1 {
-			out[i] += n[j] * float64(val)
-		}
+	out := m.Result(input)
 
-		out[i] += m.B_O[i]
-		out[i] = sigmoid(out[i])
-	}
+	return Decide(m.Outputs, out, e)
+}
 
-	return out
+// OutToAction reads the outputs of a model with the default OUTPUT_NAMES.
+func OutToAction(out Output) []ACTION {
+	return Decide(OUTPUT_NAMES, out, GameUpdateEvent{})
 }
 
 func sigmoid(x float64) float64 {
 	return (1 / (1 + math.Exp(x*(-1))))
 }
 
-func randomFloat(min, max float64) float64 {
-	return min + rand.Float64()*(max-min)
+func randomFloat(r *rand.Rand, min, max float64) float64 {
+	return min + r.Float64()*(max-min)
 }
 
 func step(x float64) float64 {
@@ -368,39 +578,16 @@ func (m Model) Copy() (*Model, error) {
 	return &clone, nil
 }
 
-func (m *Model) Mutate(rate float64) {
-	f := func(_ float64) float64 {
-		return randomFloat(-1.0, 1.0)
-	}
-
-	arr := [][]float64{}
-	for _, a := range m.W_I_H1 {
-		arr = append(arr, MutateArr(a, rate, f))
-	}
-	m.W_I_H1 = arr
-
-	arr = [][]float64{}
-	for _, a := range m.W_H1_H2 {
-		arr = append(arr, MutateArr(a, rate, f))
-	}
-	m.W_H1_H2 = arr
-	m.B_H1 = MutateArr(m.B_H1, rate, f)
-
-	arr = [][]float64{}
-	for _, a := range m.W_H2_O {
-		arr = append(arr, MutateArr(a, rate, f))
-	}
-	m.W_H2_O = arr
-	m.B_H2 = MutateArr(m.B_H2, rate, f)
-
-	m.B_O = MutateArr(m.B_O, rate, f)
+// Mutate draws a new value for each weight with the given chance.
+func (m *Model) Mutate(rate float64, r *rand.Rand) {
+	m.MutateWith(Mutation{Kind: MUTATION_RESET, Rate: rate}, r)
 }
 
-func MutateArr(arr []float64, rate float64, f func(float64) float64) []float64 {
+func MutateArr(arr []float64, rate float64, f func(float64) float64, r *rand.Rand) []float64 {
 	nArr := []float64{}
 
 	for _, val := range arr {
-		if rand.Float64() < rate {
+		if r.Float64() < rate {
 			nArr = append(nArr, f(val))
 			continue
 		}
@@ -410,16 +597,3 @@ func MutateArr(arr []float64, rate float64, f func(float64) float64) []float64 {
 
 	return nArr
 }
-
-// Get 2 models and breed then
-// func BreedModels(m1 Model, m2 Model) (M1, M2 Model) {
-// 	In := [INPUT_SIZE]float64{}
-// 	H1 := [INPUT_SIZE]float64{}
-// 	Out := [OUTPUT_SIZE]float64{}
-//
-// 	In_2 := [INPUT_SIZE]float64{}
-// 	H1_2 := [INPUT_SIZE]float64{}
-// 	Out_2 := [OUTPUT_SIZE]float64{}
-//
-// 	return In, H1, Out
-// }
@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Model files without a version are version 1, they are migrated when read
+const MODEL_VERSION = 2
+
+// Features of the default observation, in order
+var INPUT_NAMES = []string{
+	"time",
+	"coins",
+	"tech_level",
+	"tech_update_cost",
+	"mining_level",
+	"mining_update_cost",
+	"unities",
+	"enemy_unities",
+	"unity_cost",
+}
+
+// Names of the values in Output, in order
+var OUTPUT_NAMES = []string{
+	"buy_soldier",
+	"upgrade_mining",
+	"upgrade_tech",
+}
+
+// Provenance says where a trained model comes from.
+type Provenance struct {
+	Seed       uint64
+	Generation int
+	Fitness    string
+	Points     int
+	Date       time.Time
+}
+
+// migrate brings a model read from an older file up to MODEL_VERSION.
+func (m *Model) migrate() {
+	if m.Version >= MODEL_VERSION {
+		return
+	}
+
+	// Version 1 had no schema, its models were all trained on the
+	// original inputs and outputs, with unit counts truncated to whole
+	// hundreds
+	m.Inputs = []string{
+		"time",
+		"coins",
+		"tech_level",
+		"tech_update_cost",
+		"mining_level",
+		"mining_update_cost",
+		"legacy_unities",
+		"legacy_enemy_unities",
+		"unity_cost",
+	}
+	m.Outputs = slices.Clone(OUTPUT_NAMES)
+
+	m.Version = MODEL_VERSION
+}
+
+// Validate checks the model can be run: the version is known, it reads
+// known features, writes the current outputs and every layer fits the one
+// before it.
+func (m Model) Validate() error {
+	if m.Version != MODEL_VERSION {
+		return fmt.Errorf("unsupported model version %d, expected %d", m.Version, MODEL_VERSION)
+	}
+
+	if err := ValidateObservation(m.Inputs); err != nil {
+		return fmt.Errorf("model inputs: %w", err)
+	}
+	if err := ValidateOutputs(m.Outputs); err != nil {
+		return fmt.Errorf("model outputs: %w", err)
+	}
+
+	if len(m.Layers) == 0 {
+		return fmt.Errorf("model has no layers")
+	}
+
+	inputs := len(m.Inputs)
+	for i, l := range m.Layers {
+		if !isActivation(l.Activation) {
+			return fmt.Errorf("layer %d has unknown activation %q", i+1, l.Activation)
+		}
+		if len(l.Weights) == 0 {
+			return fmt.Errorf("layer %d has no neurons", i+1)
+		}
+		if len(l.Bias) != len(l.Weights) {
+			return fmt.Errorf("layer %d has %d biases for %d neurons", i+1, len(l.Bias), len(l.Weights))
+		}
+
+		for j, w := range l.Weights {
+			if len(w) != inputs {
+				return fmt.Errorf("layer %d neuron %d has %d weights, expected %d", i+1, j+1, len(w), inputs)
+			}
+		}
+
+		inputs = len(l.Weights)
+	}
+
+	if inputs != len(m.Outputs) {
+		return fmt.Errorf("output layer has %d neurons, expected %d", inputs, len(m.Outputs))
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package pkg
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestModel() Model {
+	m := Model{}
+	m.InitRandom(rand.New(rand.NewPCG(1, 2)))
+
+	return m
+}
+
+func TestValidateModel(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(m *Model)
+		err    string
+	}{
+		{
+			name: "row length",
+			change: func(m *Model) {
+				m.Layers[1].Weights[2] = m.Layers[1].Weights[2][1:]
+			},
+			err: "layer 2 neuron 3 has 11 weights, expected 12",
+		},
+		{
+			name: "bias count",
+			change: func(m *Model) {
+				m.Layers[0].Bias = m.Layers[0].Bias[1:]
+			},
+			err: "layer 1 has 11 biases for 12 neurons",
+		},
+		{
+			name: "output count",
+			change: func(m *Model) {
+				m.Outputs = m.Outputs[1:]
+			},
+			err: "output layer has 3 neurons, expected 2",
+		},
+	}
+
+	if err := newTestModel().Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel()
+			tt.change(&m)
+
+			err := m.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// legacyResult is the fixed network models were run with before Layers.
+func legacyResult(l legacyModel, input Input) Output {
+	h1 := make([]float64, len(l.W_I_H1))
+	for i, n := range l.W_I_H1 {
+		for j, in := range input {
+			h1[i] += in * n[j]
+		}
+		h1[i] = math.Tanh(h1[i] + l.B_H1[i])
+	}
+
+	h2 := make([]float64, len(l.W_H1_H2))
+	for i, n := range l.W_H1_H2 {
+		for j, val := range h1 {
+			h2[i] += n[j] * val
+		}
+		h2[i] = math.Tanh(h2[i] + l.B_H2[i])
+	}
+
+	out := make(Output, len(l.W_H2_O))
+	for i, n := range l.W_H2_O {
+		for j, val := range h2 {
+			out[i] += n[j] * val
+		}
+		out[i] = 1 / (1 + math.Exp(-(out[i] + l.B_O[i])))
+	}
+
+	return out
+}
+
+func TestMigrateLegacyModel(t *testing.T) {
+	// Written before model files had a version
+	file := filepath.Join("..", BEST_MODEL_FILE)
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := legacyModel{}
+	if err := json.Unmarshal(b, &legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadModel(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Version != MODEL_VERSION {
+		t.Fatalf("migrated to version %d, expected %d", m.Version, MODEL_VERSION)
+	}
+	if strings.Join(m.Inputs, ",") != "time,coins,tech_level,tech_update_cost,mining_level,mining_update_cost,legacy_unities,legacy_enemy_unities,unity_cost" {
+		t.Fatalf("migrated with inputs %v", m.Inputs)
+	}
+
+	r := rand.New(rand.NewPCG(3, 4))
+	for range 20 {
+		input := Input{}
+		for range m.Inputs {
+			input = append(input, randomFloat(r, -1, 1))
+		}
+		got, expected := m.Result(input), legacyResult(legacy, input)
+
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Fatalf("output %d of %v is %v, the legacy network gave %v", i, input, got[i], expected[i])
+			}
+		}
+	}
+}
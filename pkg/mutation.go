@@ -0,0 +1,115 @@
+package pkg
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Mutation kinds
+const MUTATION_RESET = "reset"
+const MUTATION_GAUSSIAN = "gaussian"
+
+const MUTATION_SIGMA = 0.1
+
+// Sigma adaptation
+const ADAPT_NONE = "none"
+const ADAPT_DECAY = "decay"
+const ADAPT_ONE_FIFTH = "one-fifth"
+
+// Sigma is multiplied or divided by this with the 1/5th success rule
+const ONE_FIFTH_FACTOR = 0.82
+
+// Decay never takes sigma under this share of its start value
+const MIN_DECAY = 0.1
+
+// Mutation says how the weights of a model are changed.
+type Mutation struct {
+	// MUTATION_RESET draws a new weight, MUTATION_GAUSSIAN adds noise to it
+	Kind string
+	// Chance of mutating each weight
+	Rate float64
+	// Rate of each layer from the input one, Rate is used past its end
+	LayerRates []float64
+	// Standard deviation of the gaussian noise
+	Sigma float64
+}
+
+func (mu Mutation) Validate() error {
+	if mu.Kind != MUTATION_RESET && mu.Kind != MUTATION_GAUSSIAN {
+		return fmt.Errorf("unknown mutation %q", mu.Kind)
+	}
+
+	return nil
+}
+
+func (mu Mutation) layerRate(layer int) float64 {
+	if layer < len(mu.LayerRates) {
+		return mu.LayerRates[layer]
+	}
+	return mu.Rate
+}
+
+// MutateWith changes the weights and biases of every layer in place.
+func (m *Model) MutateWith(mu Mutation, r *rand.Rand) {
+	f := func(_ float64) float64 {
+		return randomFloat(r, -1.0, 1.0)
+	}
+	if mu.Kind == MUTATION_GAUSSIAN {
+		f = func(v float64) float64 {
+			return v + r.NormFloat64()*mu.Sigma
+		}
+	}
+
+	for i, layer := range m.layers() {
+		for _, row := range layer {
+			copy(row, MutateArr(row, mu.layerRate(i), f, r))
+		}
+	}
+}
+
+// AdaptSigma returns the sigma of the next generation. progress goes from 0
+// on the first generation to 1 on the last one, success is the share of
+// children that beat their parents.
+func AdaptSigma(adapt string, start float64, sigma float64, progress float64, success float64) (float64, error) {
+	switch adapt {
+	case ADAPT_NONE:
+		return sigma, nil
+	case ADAPT_DECAY:
+		return start * max(1-progress, MIN_DECAY), nil
+	case ADAPT_ONE_FIFTH:
+		if success > 0.2 {
+			return sigma / ONE_FIFTH_FACTOR, nil
+		}
+		if success < 0.2 {
+			return sigma * ONE_FIFTH_FACTOR, nil
+		}
+		return sigma, nil
+	}
+
+	return sigma, fmt.Errorf("unknown sigma adaptation %q", adapt)
+}
+
+// SuccessRate is the share of children scoring above their best parent,
+// the elite, the first generation and children that did not play are left
+// out.
+func SuccessRate(pop []Individual) float64 {
+	children := 0
+	better := 0
+
+	for _, ind := range pop {
+		if !ind.Child || ind.Games == 0 {
+			continue
+		}
+
+		children++
+		if ind.Fitness > ind.ParentFitness {
+			better++
+		}
+	}
+
+	if children == 0 {
+		return 0.2
+	}
+
+	return float64(better) / float64(children)
+}
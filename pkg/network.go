@@ -0,0 +1,213 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Activations
+const ACTIVATION_TANH = "tanh"
+const ACTIVATION_RELU = "relu"
+const ACTIVATION_SIGMOID = "sigmoid"
+const ACTIVATION_LINEAR = "linear"
+
+// LayerSpec is the size and activation of a layer, the input layer has no
+// spec as its size is the number of observed features.
+type LayerSpec struct {
+	Size       int
+	Activation string
+}
+
+// The network models were trained with before topologies were configurable
+var DEFAULT_TOPOLOGY = []LayerSpec{
+	{Size: HIDDEN_LAYER, Activation: ACTIVATION_TANH},
+	{Size: HIDDEN_LAYER_2, Activation: ACTIVATION_TANH},
+	{Size: OUTPUT_SIZE, Activation: ACTIVATION_SIGMOID},
+}
+
+// Layer is a fully connected layer, Weights[i][j] links input j to
+// neuron i.
+type Layer struct {
+	Weights    [][]float64
+	Bias       []float64
+	Activation string
+}
+
+// ParseTopology reads a topology written as size:activation pairs split by
+// commas, e.g. "12:tanh,5:tanh,3:sigmoid". The last one is the output layer.
+func ParseTopology(s string) ([]LayerSpec, error) {
+	res := []LayerSpec{}
+
+	for _, part := range strings.Split(s, ",") {
+		size, act, _ := strings.Cut(strings.TrimSpace(part), ":")
+		if act == "" {
+			act = ACTIVATION_TANH
+		}
+
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, fmt.Errorf("layer %q: %w", part, err)
+		}
+
+		res = append(res, LayerSpec{Size: n, Activation: act})
+	}
+
+	return res, ValidateTopology(res)
+}
+
+func FormatTopology(t []LayerSpec) string {
+	parts := []string{}
+	for _, l := range t {
+		parts = append(parts, fmt.Sprint(l.Size, ":", l.Activation))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func ValidateTopology(t []LayerSpec) error {
+	if len(t) == 0 {
+		return errors.New("topology has no layers")
+	}
+
+	for i, l := range t {
+		if l.Size <= 0 {
+			return fmt.Errorf("layer %d has size %d", i, l.Size)
+		}
+		if !isActivation(l.Activation) {
+			return fmt.Errorf("layer %d has unknown activation %q", i, l.Activation)
+		}
+	}
+
+	return nil
+}
+
+// Topology returns the layer specs the model was built with.
+func (m Model) Topology() []LayerSpec {
+	res := []LayerSpec{}
+	for _, l := range m.Layers {
+		res = append(res, LayerSpec{Size: len(l.Weights), Activation: l.Activation})
+	}
+
+	return res
+}
+
+// Initiate all weights of the default topology with random numbers from
+// -1.0 to 1.0
+func (m *Model) InitRandom(r *rand.Rand) {
+	m.InitTopology(INPUT_NAMES, OUTPUT_NAMES, DEFAULT_TOPOLOGY, r)
+}
+
+// InitTopology replaces the layers with random ones of the given topology,
+// reading the named features and writing the named outputs. The output
+// layer of t must have a neuron for each output.
+func (m *Model) InitTopology(features []string, outputs []string, t []LayerSpec, r *rand.Rand) {
+	m.Version = MODEL_VERSION
+	m.Inputs = slices.Clone(features)
+	m.Outputs = slices.Clone(outputs)
+	m.Layers = []Layer{}
+	inputs := len(features)
+
+	for _, spec := range t {
+		l := Layer{Activation: spec.Activation}
+		for range spec.Size {
+			w := []float64{}
+			for range inputs {
+				w = append(w, randomFloat(r, -1.0, 1.0))
+			}
+			l.Weights = append(l.Weights, w)
+			l.Bias = append(l.Bias, randomFloat(r, -1.0, 1.0))
+		}
+
+		m.Layers = append(m.Layers, l)
+		inputs = spec.Size
+	}
+}
+
+func (m Model) Result(input Input) Output {
+	values := []float64(input)
+	for _, l := range m.Layers {
+		values = l.forward(values)
+	}
+
+	return Output(values)
+}
+
+func (l Layer) forward(input []float64) []float64 {
+	out := make([]float64, len(l.Weights))
+
+	for i, n := range l.Weights {
+		for j, in := range input {
+			out[i] += in * n[j]
+		}
+
+		out[i] += l.Bias[i]
+		out[i] = activate(l.Activation, out[i])
+	}
+
+	return out
+}
+
+func isActivation(name string) bool {
+	switch name {
+	case ACTIVATION_TANH, ACTIVATION_RELU, ACTIVATION_SIGMOID, ACTIVATION_LINEAR:
+		return true
+	}
+	return false
+}
+
+func activate(name string, x float64) float64 {
+	switch name {
+	case ACTIVATION_TANH:
+		return math.Tanh(x)
+	case ACTIVATION_RELU:
+		return relu(x)
+	case ACTIVATION_SIGMOID:
+		return sigmoid(x)
+	}
+	return x
+}
+
+// legacyModel is the fixed two hidden layers model files were written as
+// before Layers.
+type legacyModel struct {
+	W_I_H1  [][]float64
+	W_H1_H2 [][]float64
+	B_H1    []float64
+	W_H2_O  [][]float64
+	B_H2    []float64
+	B_O     []float64
+}
+
+// UnmarshalJSON also reads the legacy model files, turning them into the
+// default topology.
+func (m *Model) UnmarshalJSON(b []byte) error {
+	type plain Model
+	if err := json.Unmarshal(b, (*plain)(m)); err != nil {
+		return err
+	}
+
+	if len(m.Layers) == 0 {
+		l := legacyModel{}
+		if err := json.Unmarshal(b, &l); err != nil {
+			return err
+		}
+
+		if l.W_I_H1 != nil {
+			m.Layers = []Layer{
+				{Weights: l.W_I_H1, Bias: l.B_H1, Activation: ACTIVATION_TANH},
+				{Weights: l.W_H1_H2, Bias: l.B_H2, Activation: ACTIVATION_TANH},
+				{Weights: l.W_H2_O, Bias: l.B_O, Activation: ACTIVATION_SIGMOID},
+			}
+		}
+	}
+
+	m.migrate()
+
+	return nil
+}
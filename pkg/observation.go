@@ -0,0 +1,209 @@
+package pkg
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Scales the observation values are divided by, so they stay around 0..1
+const COINS_SCALE = 100.0
+const UNITIES_SCALE = 100.0
+const LEVEL_SCALE = 3.0
+
+// Most hp a unit can have left, a soldier at tech level 3 with its boost
+var MAX_UNITY_HP = math.Floor(float64(UNITY_BASE_HP[SOLDIER]*3) * TECH_BOOST[3])
+
+// Observation presets
+const OBSERVATION_DEFAULT = "default"
+const OBSERVATION_FULL = "full"
+
+// FEATURES turns a game update into the value of each named input.
+var FEATURES = map[string]func(e GameUpdateEvent) float64{
+	"time": func(e GameUpdateEvent) float64 {
+		return float64(e.Time) / FIVE_MINUTES.Seconds()
+	},
+	"coins": func(e GameUpdateEvent) float64 {
+		return float64(e.Coins) / COINS_SCALE
+	},
+	"tech_level": func(e GameUpdateEvent) float64 {
+		return float64(e.TechLevel) / LEVEL_SCALE
+	},
+	"tech_update_cost": func(e GameUpdateEvent) float64 {
+		return float64(e.TechUpdateCost) / COINS_SCALE
+	},
+	"mining_level": func(e GameUpdateEvent) float64 {
+		return float64(e.MiningLevel) / LEVEL_SCALE
+	},
+	"mining_update_cost": func(e GameUpdateEvent) float64 {
+		return float64(e.MiningUpdateCost) / COINS_SCALE
+	},
+	"unities": func(e GameUpdateEvent) float64 {
+		return float64(e.Unities) / UNITIES_SCALE
+	},
+	"enemy_unities": func(e GameUpdateEvent) float64 {
+		return float64(e.EnemyUnities) / UNITIES_SCALE
+	},
+	"unity_cost": func(e GameUpdateEvent) float64 {
+		return float64(e.UnityCost) / COINS_SCALE
+	},
+	"alive_unities": func(e GameUpdateEvent) float64 {
+		return float64(e.AliveUnities) / UNITIES_SCALE
+	},
+	"dead_unities": func(e GameUpdateEvent) float64 {
+		return float64(e.Unities-e.AliveUnities) / UNITIES_SCALE
+	},
+	"enemy_alive_unities": func(e GameUpdateEvent) float64 {
+		return float64(e.EnemyAliveUnities) / UNITIES_SCALE
+	},
+	"enemy_dead_unities": func(e GameUpdateEvent) float64 {
+		return float64(e.EnemyUnities-e.EnemyAliveUnities) / UNITIES_SCALE
+	},
+	"enemy_tech_level": func(e GameUpdateEvent) float64 {
+		return float64(e.EnemyTechLevel) / LEVEL_SCALE
+	},
+	"enemy_mining_level": func(e GameUpdateEvent) float64 {
+		return float64(e.EnemyMiningLevel) / LEVEL_SCALE
+	},
+	"border_drop_in": func(e GameUpdateEvent) float64 {
+		return float64(e.BorderDropIn) / FIVE_MINUTES.Seconds()
+	},
+	"avg_hp": func(e GameUpdateEvent) float64 {
+		return e.AvgHp / MAX_UNITY_HP
+	},
+	"enemy_avg_hp": func(e GameUpdateEvent) float64 {
+		return e.EnemyAvgHp / MAX_UNITY_HP
+	},
+	"advance": func(e GameUpdateEvent) float64 {
+		return e.Advance
+	},
+	"enemy_advance": func(e GameUpdateEvent) float64 {
+		return e.EnemyAdvance
+	},
+	"front": func(e GameUpdateEvent) float64 {
+		return e.Front
+	},
+	"enemy_front": func(e GameUpdateEvent) float64 {
+		return e.EnemyFront
+	},
+	// Unit counts as version 1 models saw them, truncated to whole
+	// hundreds so nearly always 0
+	"legacy_unities": func(e GameUpdateEvent) float64 {
+		return float64(e.Unities / 100)
+	},
+	"legacy_enemy_unities": func(e GameUpdateEvent) float64 {
+		return float64(e.EnemyUnities / 100)
+	},
+}
+
+// Every feature new models can read, the legacy ones are left out
+var FULL_OBSERVATION = append(slices.Clone(INPUT_NAMES),
+	"alive_unities",
+	"dead_unities",
+	"enemy_alive_unities",
+	"enemy_dead_unities",
+	"enemy_tech_level",
+	"enemy_mining_level",
+	"border_drop_in",
+	"avg_hp",
+	"enemy_avg_hp",
+	"advance",
+	"enemy_advance",
+	"front",
+	"enemy_front",
+)
+
+// Observe builds the network input with the named features, in order.
+func (e GameUpdateEvent) Observe(names []string) Input {
+	res := make(Input, len(names))
+	for i, name := range names {
+		if f, ok := FEATURES[name]; ok {
+			res[i] = f(e)
+		}
+	}
+
+	return res
+}
+
+// ParseObservation reads a preset name or a comma separated list of
+// features.
+func ParseObservation(s string) ([]string, error) {
+	switch s {
+	case OBSERVATION_DEFAULT:
+		return slices.Clone(INPUT_NAMES), nil
+	case OBSERVATION_FULL:
+		return slices.Clone(FULL_OBSERVATION), nil
+	}
+
+	res := []string{}
+	for _, name := range strings.Split(s, ",") {
+		res = append(res, strings.TrimSpace(name))
+	}
+
+	return res, ValidateObservation(res)
+}
+
+func ValidateObservation(names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("observation has no features")
+	}
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		if _, ok := FEATURES[name]; !ok {
+			return fmt.Errorf("unknown feature %q, expected one of %v", name, FeatureNames())
+		}
+		if seen[name] {
+			return fmt.Errorf("feature %q is observed twice", name)
+		}
+		seen[name] = true
+	}
+
+	return nil
+}
+
+// FeatureNames lists every feature, sorted.
+func FeatureNames() []string {
+	res := []string{}
+	for name := range FEATURES {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// armySummary is the average remaining HP and how far the alive units of a side have
+// gone towards the enemy base, 0 at their own base and 1 at the enemy one.
+func (g Game) armySummary(id PLAYER_TYPE) (avgHp float64, advance float64, front float64) {
+	own := g.GetBasePosition(id).Y
+	enemy := g.GetBasePosition(enemyOf(id)).Y
+	span := float64(enemy - own)
+
+	alive := g.GetAliveUnitiesByPlayerId(id)
+	if len(alive) == 0 || span == 0 {
+		return 0, 0, 0
+	}
+
+	for _, u := range alive {
+		p := min(max(float64(u.Position.Y-own)/span, 0), 1)
+		avgHp += float64(g.RemainingHp(u))
+		advance += p
+		front = max(front, p)
+	}
+
+	n := float64(len(alive))
+	return avgHp / n, advance / n, front
+}
+
+// borderDropIn is the display seconds left before the border drops on its
+// own, 0 once it is down.
+func (g Game) borderDropIn() int {
+	if !g.Field.BorderIsUp {
+		return 0
+	}
+
+	return max(int(FIVE_MINUTES.Seconds())-g.DisplayTime, 0)
+}
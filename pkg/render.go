@@ -0,0 +1,299 @@
+//go:build !headless
+
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Window renders a game with raylib and reads the keyboard.
+type Window struct {
+	// File the game is saved to when pressing F5, saving is off when empty
+	SaveFile string
+	camera   rl.Camera2D
+}
+
+func NewWindow(g *Game) *Window {
+	rl.SetTraceLogLevel(rl.LogError)
+	rl.InitWindow(int32(g.Screen.Width), int32(g.Screen.Height), fmt.Sprint("War", g.ID))
+	rl.SetTargetFPS(RENDER_FPS)
+
+	return &Window{
+		camera: rl.NewCamera2D(
+			rl.NewVector2(0, 0),
+			rl.NewVector2(0, 0),
+			0.0,
+			1.0,
+		),
+	}
+}
+
+func (w *Window) ShouldClose() bool {
+	return rl.WindowShouldClose()
+}
+
+func (w *Window) Frame(g *Game) {
+	rl.BeginDrawing()
+	rl.ClearBackground(rl.Black)
+	rl.BeginMode2D(w.camera)
+
+	if w.SaveFile != "" && rl.IsKeyPressed(rl.KeyF5) {
+		if err := SaveGame(g, w.SaveFile); err != nil {
+			fmt.Println("SAVE FAILED:", err)
+		} else {
+			fmt.Println("GAME SAVED:", w.SaveFile)
+		}
+	}
+
+	g.Render()
+	g.RenderUI()
+
+	rl.EndMode2D()
+	rl.EndDrawing()
+}
+
+func (w *Window) Close() {
+	rl.CloseWindow()
+}
+
+// RunGame plays the game in a window with one agent per player, F5 saves
+// it to saveFile when set.
+func RunGame(g *Game, agents map[PLAYER_TYPE]Agent, saveFile string) PLAYER_TYPE {
+	w := NewWindow(g)
+	w.SaveFile = saveFile
+	defer w.Close()
+
+	return RunMatch(g, agents, w)
+}
+
+type KeyBinding struct {
+	Key    int32
+	Action ACTION
+}
+
+var KEYBOARD_BINDINGS = map[PLAYER_TYPE][]KeyBinding{
+	BLUE: {
+		{Key: rl.KeyOne, Action: BUY_SOLDIER},
+		{Key: rl.KeyTwo, Action: UPDATE_MINING},
+		{Key: rl.KeyThree, Action: UPDATE_TECH},
+		{Key: rl.KeyFour, Action: BUY_BOMBER},
+		{Key: rl.KeyFive, Action: StanceAction(STANCE_ADVANCE)},
+		{Key: rl.KeySix, Action: StanceAction(STANCE_HOLD)},
+		{Key: rl.KeySeven, Action: StanceAction(STANCE_FOCUS_NEAREST)},
+		{Key: rl.KeyEight, Action: StanceAction(STANCE_FOCUS_WEAKEST)},
+		{Key: rl.KeyNine, Action: StanceAction(STANCE_RETREAT)},
+		{Key: rl.KeySpace, Action: DROP_BORDER},
+	},
+	RED: {
+		{Key: rl.KeyQ, Action: BUY_SOLDIER},
+		{Key: rl.KeyW, Action: UPDATE_MINING},
+		{Key: rl.KeyE, Action: UPDATE_TECH},
+		{Key: rl.KeyR, Action: BUY_BOMBER},
+		{Key: rl.KeyA, Action: StanceAction(STANCE_ADVANCE)},
+		{Key: rl.KeyS, Action: StanceAction(STANCE_HOLD)},
+		{Key: rl.KeyD, Action: StanceAction(STANCE_FOCUS_NEAREST)},
+		{Key: rl.KeyF, Action: StanceAction(STANCE_FOCUS_WEAKEST)},
+		{Key: rl.KeyG, Action: StanceAction(STANCE_RETREAT)},
+	},
+}
+
+// KeyboardAgent lets a human play, key presses are queued every frame and
+// sent on the next update.
+type KeyboardAgent struct {
+	Bindings []KeyBinding
+	pending  []ACTION
+}
+
+func NewKeyboardAgent(p PLAYER_TYPE) (Agent, error) {
+	return &KeyboardAgent{Bindings: KEYBOARD_BINDINGS[p]}, nil
+}
+
+func (k *KeyboardAgent) Poll() {
+	for _, b := range k.Bindings {
+		if rl.IsKeyPressed(b.Key) {
+			k.pending = append(k.pending, b.Action)
+		}
+	}
+}
+
+func (k *KeyboardAgent) Act(e GameUpdateEvent) []ACTION {
+	res := k.pending
+	k.pending = nil
+
+	return res
+}
+
+func (k *KeyboardAgent) Rejected(action ACTION, err error) {
+	fmt.Println("ACTION REJECTED:", action, err)
+}
+
+func (u Unity) GetColor() rl.Color {
+	if u.State == DEAD {
+		return rl.Gray
+	}
+
+	switch u.PlayerOwner {
+	case RED:
+		return rl.Red
+	case BLUE:
+		return rl.Blue
+	}
+
+	return rl.White
+}
+
+func (u Tower) GetColor() rl.Color {
+	switch u.PlayerOwner {
+	case RED:
+		return rl.Red
+	case BLUE:
+		return rl.Blue
+	}
+
+	return rl.White
+}
+
+func (g *Game) Render() {
+	// Field
+	rec := rl.NewRectangle(0, 0, float32(g.Field.Width), float32(g.Field.Height))
+	rl.DrawRectangleLinesEx(rec, 3, rl.White)
+
+	// Border
+	if g.Field.BorderIsUp {
+		rl.DrawRectangle(0, int32(g.Screen.Height/2), int32(g.Screen.Width), 5, rl.Orange)
+	}
+
+	// Towers
+	for _, t := range g.Towers {
+		switch t.Type {
+		case BASE:
+			rl.DrawRectangle(int32(t.Position.X), int32(t.Position.Y), int32(t.Thickness), int32(t.Thickness), t.GetColor())
+		}
+	}
+
+	// Unities
+	for _, u := range g.Unities {
+		if u.State == DEAD {
+			continue
+		}
+
+		t := UNITY_THICK[u.Type]
+
+		switch u.Type {
+		case SOLDIER:
+			x := float64(u.Position.X) - float64(t)/2
+			y := float64(u.Position.Y) - float64(t)/2
+			rec := rl.NewRectangle(float32(x), float32(y), float32(t), float32(t))
+
+			rl.DrawRectangleRec(rec, u.GetColor())
+		case BOMBER:
+			rl.DrawCircle(int32(u.Position.X), int32(u.Position.Y), float32(t)/2, u.GetColor())
+		}
+	}
+}
+
+func (g *Game) RenderUI() {
+	rl.DrawText(fmt.Sprint("Time:", g.DisplayTime), 0, 0, 16, rl.White)
+	rl.DrawText(fmt.Sprint("Coins Blue:", g.PlayerBlue.Coins), 0, 25, 16, rl.White)
+	rl.DrawText(fmt.Sprint("Coins Red:", g.PlayerRed.Coins), 0, 45, 16, rl.White)
+
+	rl.DrawText(fmt.Sprint("Mining Level Blue:", g.PlayerBlue.MiningLevel), 0, 65, 16, rl.White)
+	rl.DrawText(fmt.Sprint("Tech Level Blue:", g.PlayerBlue.TechnologyLevel), 0, 85, 16, rl.White)
+
+	rl.DrawText(fmt.Sprint("Mining Level Red:", g.PlayerRed.MiningLevel), 0, 105, 16, rl.White)
+	rl.DrawText(fmt.Sprint("Tech Level Red:", g.PlayerRed.TechnologyLevel), 0, 125, 16, rl.White)
+
+	var aliveUnities = 0
+	var deadUnitites = 0
+	for _, u := range g.Unities {
+		if u.State != DEAD {
+			aliveUnities += 1
+		}
+		if u.State == DEAD {
+			deadUnitites++
+		}
+	}
+
+	rl.DrawText(fmt.Sprint("Unities:", aliveUnities), 0, 145, 16, rl.White)
+	rl.DrawText(fmt.Sprint("Dead Unities:", deadUnitites), 0, 160, 16, rl.White)
+}
+
+// WatchReplay plays a replay in a window starting at the given tick.
+//
+//	Space        pause / resume
+//	Right        step one tick
+//	Up / Down    double / halve the speed
+//	R            restart
+//	0-9, Enter   jump to the typed tick
+func WatchReplay(r *Replay, tick int) {
+	p := NewReplayPlayer(r)
+	p.Seek(tick)
+
+	w := NewWindow(p.Game)
+	defer w.Close()
+
+	speed := 1
+	paused := false
+	jump := ""
+
+	for !w.ShouldClose() {
+		switch {
+		case rl.IsKeyPressed(rl.KeySpace):
+			paused = !paused
+		case rl.IsKeyPressed(rl.KeyRight):
+			paused = true
+			p.Advance(1)
+		case rl.IsKeyPressed(rl.KeyUp):
+			speed = min(speed*2, 64)
+		case rl.IsKeyPressed(rl.KeyDown):
+			speed = max(speed/2, 1)
+		case rl.IsKeyPressed(rl.KeyR):
+			p.Restart()
+		case rl.IsKeyPressed(rl.KeyBackspace) && jump != "":
+			jump = jump[:len(jump)-1]
+		case rl.IsKeyPressed(rl.KeyEnter) && jump != "":
+			t, _ := strconv.Atoi(jump)
+			p.Seek(t)
+			jump = ""
+		}
+
+		for k := int32(rl.KeyZero); k <= rl.KeyNine; k++ {
+			if rl.IsKeyPressed(k) {
+				jump += strconv.Itoa(int(k - rl.KeyZero))
+			}
+		}
+
+		if !paused {
+			p.Advance(speed)
+		}
+
+		rl.BeginDrawing()
+		rl.ClearBackground(rl.Black)
+		rl.BeginMode2D(w.camera)
+
+		p.Game.Render()
+		p.Game.RenderUI()
+
+		status := fmt.Sprint("Tick:", p.Game.Tick, "/", r.Ticks, " x", speed)
+		if paused {
+			status += " PAUSED"
+		}
+		if p.Done() {
+			status += fmt.Sprint(" WINNER:", p.Game.Winner)
+		}
+		if jump != "" {
+			status += " Jump:" + jump
+		}
+		rl.DrawText(status, 0, int32(p.Game.Screen.Height)-20, 16, rl.White)
+
+		if p.Desync != nil {
+			rl.DrawText(fmt.Sprint("DESYNC AT TICK:", p.Desync.Tick), 0, int32(p.Game.Screen.Height)-40, 16, rl.Red)
+		}
+
+		rl.EndMode2D()
+		rl.EndDrawing()
+	}
+}
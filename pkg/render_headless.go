@@ -0,0 +1,28 @@
+//go:build headless
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Builds tagged headless have no raylib, games always run without a window.
+func RunGame(g *Game, agents map[PLAYER_TYPE]Agent, saveFile string) PLAYER_TYPE {
+	return RunMatch(g, agents, nil)
+}
+
+// There is no keyboard without a window, human players can not play.
+func NewKeyboardAgent(p PLAYER_TYPE) (Agent, error) {
+	return nil, errors.New("built headless, there is no keyboard to play with")
+}
+
+// Without a window the replay is only verified.
+func WatchReplay(r *Replay, tick int) {
+	if err := VerifyReplay(r); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("REPLAY OK, WINNER:", r.Winner)
+}
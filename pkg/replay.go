@@ -0,0 +1,321 @@
+package pkg
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+)
+
+const REPLAY_VERSION = 1
+
+// Ticks between two checksums of a recorded game
+const CHECKSUM_INTERVAL = 10
+
+// Replay holds everything needed to play a game again: the seed, the state
+// it started from and every accepted action with the tick it was taken at. The
+// simulation is deterministic so nothing else is stored, the checksums of
+// the state taken while recording tell when playing it back diverged.
+type Replay struct {
+	Version   int              `json:"v"`
+	Seed      uint64           `json:"seed"`
+	Start     json.RawMessage  `json:"start"`
+	Actions   []ReplayAction   `json:"actions"`
+	Checksums []ReplayChecksum `json:"sums"`
+	Winner    PLAYER_TYPE      `json:"winner"`
+	Ticks     int              `json:"ticks"`
+}
+
+type ReplayChecksum struct {
+	Tick int    `json:"t"`
+	Sum  uint64 `json:"s"`
+}
+
+// Desync is the first checksum of a replay that did not match when it was
+// played again, the divergence happened after LastMatch.
+type Desync struct {
+	Tick      int
+	LastMatch int
+	Expected  uint64
+	Got       uint64
+}
+
+func (d *Desync) Error() string {
+	return fmt.Sprintf("replay diverged between tick %d and %d: expected checksum %x, got %x", d.LastMatch, d.Tick, d.Expected, d.Got)
+}
+
+type ReplayAction struct {
+	Tick   int         `json:"t"`
+	Owner  PLAYER_TYPE `json:"o"`
+	Action ACTION      `json:"a"`
+}
+
+// Record starts recording the game, call it after Init and before the
+// first step. The replay is complete once the game finishes.
+func (g *Game) Record() *Replay {
+	start, _ := g.Snapshot()
+
+	g.recording = &Replay{
+		Version: REPLAY_VERSION,
+		Seed:    g.Seed,
+		Start:   start,
+		Actions: []ReplayAction{},
+	}
+
+	return g.recording
+}
+
+func (r *Replay) checkpoint(g *Game) {
+	if g.Tick%CHECKSUM_INTERVAL != 0 && g.Winner == "" {
+		return
+	}
+
+	r.Checksums = append(r.Checksums, ReplayChecksum{Tick: g.Tick, Sum: g.Checksum()})
+}
+
+// Checksum hashes the state the simulation depends on: the clock, the
+// players and every unit.
+func (g *Game) Checksum() uint64 {
+	h := fnv.New64a()
+	write := func(values ...int) {
+		for _, v := range values {
+			binary.Write(h, binary.LittleEndian, int64(v))
+		}
+	}
+
+	border := 0
+	if g.Field.BorderIsUp {
+		border = 1
+	}
+	write(g.Tick, g.DisplayTime, border)
+
+	for _, p := range []Player{g.PlayerBlue, g.PlayerRed} {
+		write(p.Coins, p.TotalCoins, p.TechnologyLevel, p.MiningLevel)
+	}
+
+	for _, u := range g.Unities {
+		write(u.Id, u.Position.X, u.Position.Y, u.Hp, u.AcumulatedDamage, int(u.State))
+	}
+
+	return h.Sum64()
+}
+
+// WriteReplay saves the replay as gzipped JSON.
+func WriteReplay(r *Replay, file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	if err := json.NewEncoder(zw).Encode(r); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func LoadReplay(file string) (*Replay, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	defer zr.Close()
+
+	var r Replay
+	if err := json.NewDecoder(zr).Decode(&r); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	if r.Version != REPLAY_VERSION {
+		return nil, fmt.Errorf("%s: unsupported replay version %d", file, r.Version)
+	}
+
+	if len(r.Start) == 0 {
+		return nil, errors.New(file + ": replay has no start state")
+	}
+
+	if _, err := RestoreGame(r.Start); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return &r, nil
+}
+
+// ReplayAgent takes the recorded actions of one player again, each one on
+// the update of the tick it was taken at.
+type ReplayAgent struct {
+	Actions []ReplayAction
+	next    int
+}
+
+func (a *ReplayAgent) Act(e GameUpdateEvent) []ACTION {
+	res := []ACTION{}
+
+	for a.next < len(a.Actions) && a.Actions[a.next].Tick <= e.Tick {
+		if a.Actions[a.next].Tick == e.Tick {
+			res = append(res, a.Actions[a.next].Action)
+		}
+		a.next++
+	}
+
+	return res
+}
+
+// Agents returns a ReplayAgent for each player.
+func (r *Replay) Agents() map[PLAYER_TYPE]Agent {
+	agents := map[PLAYER_TYPE]Agent{}
+
+	for _, p := range []PLAYER_TYPE{BLUE, RED} {
+		a := &ReplayAgent{}
+		for _, action := range r.Actions {
+			if action.Owner == p {
+				a.Actions = append(a.Actions, action)
+			}
+		}
+
+		agents[p] = a
+	}
+
+	return agents
+}
+
+// NewGame returns the game the replay was recorded from, before its first
+// step.
+func (r *Replay) NewGame() Game {
+	// Checked by LoadReplay
+	g, _ := RestoreGame(r.Start)
+	g.Speed = 1
+
+	return g
+}
+
+// ReplayPlayer simulates a replay again one tick at a time, it can be
+// paused, moved forward or sent back to any tick. Every tick with a
+// recorded checksum is verified, Desync is set on the first mismatch.
+type ReplayPlayer struct {
+	Replay *Replay
+	Game   *Game
+	Desync *Desync
+
+	checksum  int
+	lastMatch int
+}
+
+func NewReplayPlayer(r *Replay) *ReplayPlayer {
+	p := &ReplayPlayer{Replay: r}
+	p.Restart()
+
+	return p
+}
+
+// Restart goes back to the state before the first tick.
+func (p *ReplayPlayer) Restart() {
+	g := p.Replay.NewGame()
+	AttachAgents(&g, p.Replay.Agents())
+
+	p.Game = &g
+	p.Desync = nil
+	p.checksum = 0
+	p.lastMatch = 0
+}
+
+// Advance simulates up to n ticks, it stops when the game is over.
+func (p *ReplayPlayer) Advance(n int) {
+	for range n {
+		if p.Done() {
+			return
+		}
+
+		p.Game.Step()
+		p.verify()
+
+		if p.Game.Winner != "" {
+			p.Game.Finish()
+		}
+	}
+}
+
+func (p *ReplayPlayer) verify() {
+	sums := p.Replay.Checksums
+	for p.checksum < len(sums) && sums[p.checksum].Tick < p.Game.Tick {
+		p.checksum++
+	}
+
+	if p.checksum >= len(sums) || sums[p.checksum].Tick != p.Game.Tick {
+		return
+	}
+
+	expected := sums[p.checksum].Sum
+	p.checksum++
+
+	if p.Desync != nil {
+		return
+	}
+
+	if got := p.Game.Checksum(); got != expected {
+		p.Desync = &Desync{
+			Tick:      p.Game.Tick,
+			LastMatch: p.lastMatch,
+			Expected:  expected,
+			Got:       got,
+		}
+		return
+	}
+
+	p.lastMatch = p.Game.Tick
+}
+
+// Seek moves the game to the given tick, restarting it when the tick is
+// already in the past.
+func (p *ReplayPlayer) Seek(tick int) {
+	if tick < p.Game.Tick {
+		p.Restart()
+	}
+
+	p.Advance(tick - p.Game.Tick)
+}
+
+func (p *ReplayPlayer) Done() bool {
+	return p.Game.Finished
+}
+
+// PlayReplay simulates the whole replay headless and returns the winner.
+func PlayReplay(r *Replay) PLAYER_TYPE {
+	p := NewReplayPlayer(r)
+	for !p.Done() {
+		p.Advance(r.Ticks + 1)
+	}
+
+	return p.Game.Winner
+}
+
+// VerifyReplay simulates the whole replay and returns a *Desync when the
+// state does not match the recorded checksums, or an error when the game
+// ends differently.
+func VerifyReplay(r *Replay) error {
+	p := NewReplayPlayer(r)
+	for !p.Done() {
+		p.Advance(r.Ticks + 1)
+	}
+
+	if p.Desync != nil {
+		return p.Desync
+	}
+
+	if p.Game.Winner != r.Winner || p.Game.Tick != r.Ticks {
+		return fmt.Errorf("replay ended at tick %d won by %s, recorded tick %d won by %s", p.Game.Tick, p.Game.Winner, r.Ticks, r.Winner)
+	}
+
+	return nil
+}
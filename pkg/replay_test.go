@@ -0,0 +1,79 @@
+package pkg
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// battleAgents build an army on both sides and drop the border at 50s, so
+// the game ends in a real fight.
+func battleAgents() map[PLAYER_TYPE]Agent {
+	return map[PLAYER_TYPE]Agent{
+		BLUE: NewScriptedAgent([]ScriptStep{
+			{Time: 1, Action: BUY_SOLDIER},
+			{Time: 2, Action: BuyAction(SOLDIER, 3, 1)},
+			{Time: 45, Action: UPDATE_TECH},
+			{Time: 48, Action: BUY_SOLDIER},
+			{Time: 50, Action: DROP_BORDER},
+		}),
+		RED: NewScriptedAgent([]ScriptStep{
+			{Time: 1, Action: BuyAction(SOLDIER, 2, 0)},
+			{Time: 3, Action: BUY_BOMBER},
+			{Time: 8, Action: StanceAction(STANCE_FOCUS_WEAKEST)},
+			{Time: 10, Action: BUY_SOLDIER},
+		}),
+	}
+}
+
+func newBattle(seed uint64) *Game {
+	g := NewGame(CreateGameArgs{Speed: 1, Seed: seed})
+	g.Init()
+	AttachAgents(&g, battleAgents())
+
+	return &g
+}
+
+func TestReplayVerifies(t *testing.T) {
+	g := newBattle(42)
+	r := g.Record()
+	winner := RunHeadless(g)
+
+	if len(r.Actions) == 0 {
+		t.Fatal("no action was recorded")
+	}
+
+	file := filepath.Join(t.TempDir(), "battle.replay")
+	if err := WriteReplay(r, file); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadReplay(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyReplay(loaded); err != nil {
+		t.Fatal(err)
+	}
+	if got := PlayReplay(loaded); got != winner {
+		t.Fatalf("replay won by %s, game won by %s", got, winner)
+	}
+}
+
+func TestReplayDesync(t *testing.T) {
+	g := newBattle(42)
+	r := g.Record()
+	RunHeadless(g)
+
+	i := len(r.Checksums) / 2
+	r.Checksums[i].Sum++
+
+	err := VerifyReplay(r)
+	desync := &Desync{}
+	if !errors.As(err, &desync) {
+		t.Fatalf("expected a desync, got %v", err)
+	}
+	if desync.Tick != r.Checksums[i].Tick {
+		t.Fatalf("desync found at tick %d, expected %d", desync.Tick, r.Checksums[i].Tick)
+	}
+}
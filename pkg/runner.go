@@ -0,0 +1,42 @@
+package pkg
+
+// Frame rate of the raylib window, at Speed 1 a rendered game plays in real
+// time.
+const RENDER_FPS = TICK_RATE
+
+// Renderer shows a running Game and reads input from whoever is watching
+// it. The simulation itself never depends on one, see RunHeadless.
+type Renderer interface {
+	// ShouldClose reports whether the viewer asked to stop the match
+	ShouldClose() bool
+	// Frame handles input and draws the game after each step
+	Frame(g *Game)
+	Close()
+}
+
+// RunHeadless plays the game until there is a winner without opening a
+// window, so it can run on servers and CI.
+func RunHeadless(g *Game) PLAYER_TYPE {
+	return RunWithRenderer(g, nil)
+}
+
+// RunWithRenderer plays the game until there is a winner, handing every
+// step to r. A nil renderer runs the game headless, the result is the same
+// either way since the simulation only follows its own clock.
+func RunWithRenderer(g *Game, r Renderer) PLAYER_TYPE {
+	for g.Winner == "" {
+		if r != nil && r.ShouldClose() {
+			break
+		}
+
+		g.Step()
+
+		if r != nil {
+			r.Frame(g)
+		}
+	}
+
+	g.Finish()
+
+	return g.Winner
+}
@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+)
+
+const SNAPSHOT_VERSION = 1
+
+// Snapshot is the full state of a game: everything exported in Game plus
+// the state of its random source, so a restored game goes on exactly like
+// the original would have.
+type Snapshot struct {
+	Version int
+	Game    *Game
+	Rng     []byte
+}
+
+// Snapshot serializes the game to JSON. Subscriptions and recordings are
+// not part of it.
+func (g *Game) Snapshot() ([]byte, error) {
+	rng, err := g.rngSource.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(Snapshot{
+		Version: SNAPSHOT_VERSION,
+		Game:    g,
+		Rng:     rng,
+	})
+}
+
+// RestoreGame loads a game serialized with Snapshot, it continues from the
+// tick it was taken at and has no subscribers.
+func RestoreGame(data []byte) (Game, error) {
+	s := Snapshot{}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Game{}, err
+	}
+
+	if s.Version != SNAPSHOT_VERSION {
+		return Game{}, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+
+	if s.Game == nil {
+		return Game{}, errors.New("snapshot has no game")
+	}
+
+	src := &rand.PCG{}
+	if err := src.UnmarshalBinary(s.Rng); err != nil {
+		return Game{}, fmt.Errorf("snapshot random source: %w", err)
+	}
+
+	g := *s.Game
+	g.rng = rand.New(src)
+	g.rngSource = src
+	g.events = newEventBus()
+
+	return g, nil
+}
+
+func SaveGame(g *Game, file string) error {
+	b, err := g.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, b, 0644)
+}
+
+func LoadGame(file string) (Game, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return Game{}, err
+	}
+
+	g, err := RestoreGame(b)
+	if err != nil {
+		return Game{}, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return g, nil
+}
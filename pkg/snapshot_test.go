@@ -0,0 +1,38 @@
+package pkg
+
+import "testing"
+
+func TestRestoreMidWar(t *testing.T) {
+	g := newBattle(7)
+	for g.Field.BorderIsUp {
+		g.Step()
+	}
+	for range 100 {
+		g.Step()
+	}
+	if g.Winner != "" {
+		t.Fatalf("game ended at tick %d, before the snapshot", g.Tick)
+	}
+
+	b, err := g.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := RestoreGame(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for g.Winner == "" || restored.Winner == "" {
+		g.Step()
+		restored.Step()
+
+		if g.Tick != restored.Tick || g.Checksum() != restored.Checksum() {
+			t.Fatalf("restored game diverged at tick %d", g.Tick)
+		}
+	}
+
+	if g.Winner != restored.Winner {
+		t.Fatalf("restored game won by %s, original by %s", restored.Winner, g.Winner)
+	}
+}
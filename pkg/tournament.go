@@ -0,0 +1,187 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"text/tabwriter"
+)
+
+const TOURNAMENT_SEEDS = 3
+
+// TournamentConfig plays every model against every other one, with both
+// colours on each game seed.
+type TournamentConfig struct {
+	Models []string
+	// Game seeds per pairing, each one is played with both colours
+	Seeds int
+	// Seed the game seeds are drawn from, a random one is picked when zero
+	Seed    uint64
+	Workers int
+	// Ladder file updated with every game, off when empty
+	Ladder string
+	// Actions allowed once the border is down
+	WarRules WarRules
+}
+
+type Standing struct {
+	Model  string
+	Games  int
+	Wins   int
+	Losses int
+	Draws  int
+	Score  int
+}
+
+func (s Standing) AverageScore() float64 {
+	if s.Games == 0 {
+		return 0
+	}
+
+	return float64(s.Score) / float64(s.Games)
+}
+
+// TournamentGame is one game of the round-robin, Blue and Red index the
+// models of the config.
+type TournamentGame struct {
+	Blue  int
+	Red   int
+	Train Train
+}
+
+// RunTournament plays the round-robin and returns the standings, best
+// first.
+func RunTournament(cfg TournamentConfig) ([]Standing, []TournamentGame, error) {
+	if len(cfg.Models) < 2 {
+		return nil, nil, fmt.Errorf("a tournament needs at least 2 models, got %d", len(cfg.Models))
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = NewSeed()
+	}
+	if cfg.Seeds <= 0 {
+		cfg.Seeds = TOURNAMENT_SEEDS
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	fmt.Println("TOURNAMENT SEED:", cfg.Seed)
+
+	models := []Model{}
+	for _, file := range cfg.Models {
+		m, err := LoadModel(file)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		models = append(models, m)
+	}
+
+	rng := NewRand(cfg.Seed)
+	games := []TournamentGame{}
+
+	for i := range models {
+		for j := i + 1; j < len(models); j++ {
+			for range cfg.Seeds {
+				seed := rng.Uint64()
+				games = append(games, newTournamentGame(models, i, j, seed, cfg.WarRules), newTournamentGame(models, j, i, seed, cfg.WarRules))
+			}
+		}
+	}
+
+	trains := make([]Train, len(games))
+	for i, tg := range games {
+		trains[i] = tg.Train
+	}
+	PlayTrains(trains, cfg.Workers)
+
+	standings := make([]Standing, len(models))
+	for i, file := range cfg.Models {
+		standings[i].Model = file
+	}
+
+	for i := range games {
+		games[i].Train = trains[i]
+		t := trains[i]
+		blue, red := &standings[games[i].Blue], &standings[games[i].Red]
+
+		blue.Games++
+		red.Games++
+		blue.Score += t.Game.PlayerPoints(BLUE)
+		red.Score += t.Game.PlayerPoints(RED)
+
+		switch {
+		case t.Game.Draw:
+			blue.Draws++
+			red.Draws++
+		case t.Winner == BLUE:
+			blue.Wins++
+			red.Losses++
+		default:
+			red.Wins++
+			blue.Losses++
+		}
+	}
+
+	if cfg.Ladder != "" {
+		err := UpdateLadder(cfg.Ladder, func(l *Ladder) error {
+			for _, file := range cfg.Models {
+				if err := l.Track(file); err != nil {
+					return err
+				}
+			}
+
+			for _, tg := range games {
+				l.RecordTrain(cfg.Models[tg.Blue], cfg.Models[tg.Red], tg.Train)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	sort.SliceStable(standings, func(a, b int) bool {
+		if standings[a].Wins != standings[b].Wins {
+			return standings[a].Wins > standings[b].Wins
+		}
+		if standings[a].Draws != standings[b].Draws {
+			return standings[a].Draws > standings[b].Draws
+		}
+		return standings[a].AverageScore() > standings[b].AverageScore()
+	})
+
+	return standings, games, nil
+}
+
+func newTournamentGame(models []Model, blue int, red int, seed uint64, rules WarRules) TournamentGame {
+	g := NewGame(CreateGameArgs{Speed: 32, Seed: seed, WarRules: rules})
+	g.Init()
+
+	mB, _ := models[blue].Copy()
+	mB.Type = BLUE
+	mR, _ := models[red].Copy()
+	mR.Type = RED
+
+	return TournamentGame{
+		Blue: blue,
+		Red:  red,
+		Train: Train{
+			Game:      &g,
+			ModelBlue: mB,
+			ModelRed:  mR,
+			Seed:      seed,
+		},
+	}
+}
+
+func WriteStandings(standings []Standing, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "#\tModel\tGames\tWins\tLosses\tDraws\tAvg Score\t")
+	for i, s := range standings {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%d\t%d\t%d\t%.1f\t\n", i+1, s.Model, s.Games, s.Wins, s.Losses, s.Draws, s.AverageScore())
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,221 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WarRules says what players can still do once the border is down. The zero
+// value allows nothing, as games always were before the rules.
+type WarRules struct {
+	// Units can be bought, at ReinforcementCost percent of their price
+	Reinforcements    bool
+	ReinforcementCost int
+	// Tech can be upgraded, mining too when Income is on
+	Upgrades bool
+	// Mining keeps paying coins
+	Income bool
+	// Stances other than retreat can be changed
+	Stances bool
+	// The army can be sent back to its base with STANCE_RETREAT
+	Retreat bool
+}
+
+// Price of reinforcements when no cost is given, in percent
+const REINFORCEMENT_COST = 200
+
+// War rules presets
+const WAR_RULES_NONE = "none"
+const WAR_RULES_OPEN = "open"
+
+var WAR_RULES = map[string]WarRules{
+	WAR_RULES_NONE: {},
+	WAR_RULES_OPEN: {
+		Reinforcements:    true,
+		ReinforcementCost: REINFORCEMENT_COST,
+		Upgrades:          true,
+		Income:            true,
+		Stances:           true,
+		Retreat:           true,
+	},
+}
+
+// Names of the single rules, see ParseWarRules
+const WAR_RULE_REINFORCEMENTS = "reinforcements"
+const WAR_RULE_UPGRADES = "upgrades"
+const WAR_RULE_INCOME = "income"
+const WAR_RULE_STANCES = "stances"
+const WAR_RULE_RETREAT = "retreat"
+
+// ParseWarRules reads a preset name or a comma separated list of rules,
+// reinforcements can be given a cost in percent, e.g.
+// "reinforcements:150,retreat".
+func ParseWarRules(s string) (WarRules, error) {
+	if r, ok := WAR_RULES[s]; ok {
+		return r, nil
+	}
+
+	r := WarRules{}
+	for _, part := range strings.Split(s, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), ":")
+
+		if arg != "" && name != WAR_RULE_REINFORCEMENTS {
+			return r, fmt.Errorf("war rule %q takes no argument", name)
+		}
+
+		switch name {
+		case WAR_RULE_REINFORCEMENTS:
+			r.Reinforcements = true
+			r.ReinforcementCost = REINFORCEMENT_COST
+			if arg != "" {
+				cost, err := strconv.Atoi(arg)
+				if err != nil || cost <= 0 {
+					return r, fmt.Errorf("invalid reinforcement cost %q", arg)
+				}
+				r.ReinforcementCost = cost
+			}
+		case WAR_RULE_UPGRADES:
+			r.Upgrades = true
+		case WAR_RULE_INCOME:
+			r.Income = true
+		case WAR_RULE_STANCES:
+			r.Stances = true
+		case WAR_RULE_RETREAT:
+			r.Retreat = true
+		default:
+			return r, fmt.Errorf("unknown war rule %q, expected one of %v or %v", name, WarRulesPresets(), []string{WAR_RULE_REINFORCEMENTS, WAR_RULE_UPGRADES, WAR_RULE_INCOME, WAR_RULE_STANCES, WAR_RULE_RETREAT})
+		}
+	}
+
+	return r, nil
+}
+
+// String writes the rules the way ParseWarRules reads them.
+func (r WarRules) String() string {
+	parts := []string{}
+	if r.Reinforcements {
+		parts = append(parts, fmt.Sprint(WAR_RULE_REINFORCEMENTS, ":", r.ReinforcementCost))
+	}
+	if r.Upgrades {
+		parts = append(parts, WAR_RULE_UPGRADES)
+	}
+	if r.Income {
+		parts = append(parts, WAR_RULE_INCOME)
+	}
+	if r.Stances {
+		parts = append(parts, WAR_RULE_STANCES)
+	}
+	if r.Retreat {
+		parts = append(parts, WAR_RULE_RETREAT)
+	}
+
+	if len(parts) == 0 {
+		return WAR_RULES_NONE
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// WarRulesPresets lists the presets, sorted.
+func WarRulesPresets() []string {
+	res := []string{}
+	for name := range WAR_RULES {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// Allow returns why an action can not be taken once the border is down, nil
+// when the rules let it through.
+func (r WarRules) Allow(a ACTION) error {
+	allowed := false
+
+	switch a {
+	case DO_NOTHING:
+		allowed = true
+	case BUY_SOLDIER, BUY_BOMBER:
+		allowed = r.Reinforcements
+	case UPDATE_TECH:
+		allowed = r.Upgrades
+	case UPDATE_MINING:
+		if r.Upgrades && !r.Income {
+			return errors.New("Mining pays nothing once the border is down")
+		}
+		allowed = r.Upgrades
+	case DROP_BORDER:
+		return errors.New("Border is already down")
+	default:
+		c, err := ParseAction(a)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case c.Name == BUY_PREFIX:
+			allowed = r.Reinforcements
+		case c.Stance == STANCE_RETREAT:
+			allowed = r.Retreat
+		default:
+			allowed = r.Stances
+		}
+	}
+
+	if !allowed {
+		return fmt.Errorf("%s is not allowed once the border is down", a)
+	}
+
+	return nil
+}
+
+// reinforcementCost is the price of a unit bought during the war.
+func (r WarRules) reinforcementCost(cost int) int {
+	if r.ReinforcementCost <= 0 {
+		return cost
+	}
+
+	return cost * r.ReinforcementCost / 100
+}
+
+// retreat moves a unit back to the front of its base, where it waits for
+// the stance to change. On the way it hits back any enemy it is in contact
+// with, without stopping.
+func (g *Game) retreat(i int, now float64) {
+	u := g.Unities[i]
+	bP := g.GetBasePosition(u.PlayerOwner)
+
+	target := Position{X: u.Position.X, Y: bP.Y + BASE_THICKNESS + RETREAT_DISTANCE}
+	if u.PlayerOwner == RED {
+		target.Y = bP.Y - BASE_THICKNESS - RETREAT_DISTANCE
+	}
+
+	g.Unities[i].State = IDDLE
+	g.Unities[i].TargetUnityId = 0
+
+	if u.getCoolDown(now) == 0 {
+		box := u.GetCollisionBox()
+		for _, e := range g.Unities {
+			if e.State != DEAD && e.PlayerOwner != u.PlayerOwner && box.Collides(e.GetCollisionBox()) {
+				g.ExecuteDamage(u.Id, e.Id, g.CalculateUnityDamage(u, e))
+				g.Unities[i].LastAttackAt = now
+				break
+			}
+		}
+	}
+
+	dy := target.Y - u.Position.Y
+	if dy == 0 {
+		return
+	}
+
+	step := min(u.Speed, max(dy, -dy))
+	if dy < 0 {
+		step = -step
+	}
+
+	g.UpdateUnityPosition(i, Position{X: u.Position.X, Y: u.Position.Y + step})
+}